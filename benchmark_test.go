@@ -0,0 +1,76 @@
+package go_template
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+var benchContext = func() string {
+	items := make([]string, 0, 50)
+	for i := 0; i < 50; i++ {
+		items = append(items, fmt.Sprintf(`{"name": "token%d", "amount": "%d000000000000000000", "price": %d.25}`, i, i+1, i+1))
+	}
+	return `{"tx": {"hash": "0xabc", "value": "8912239900000000000", "gas": 21000,
+		"from": {"address": "0x1", "label": "alice"}, "to": {"address": "0x2", "label": "bob"}},
+		"items": [` + strings.Join(items, ",") + `]}`
+}()
+
+func benchmarkRender(b *testing.B, text string) {
+	tp, err := NewTemplate(text, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := tp.Render(benchContext); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkRender_Literals(b *testing.B) {
+	benchmarkRender(b, "{1 + 2 * 3} {1e18 / 1e9} {round(1.23456, 2)}")
+}
+
+func BenchmarkRender_Variables(b *testing.B) {
+	benchmarkRender(b, "{$tx.hash} {$tx.from.label} sent {$tx.value / 1e18} to {$tx.to.label}, gas {$tx.gas}")
+}
+
+func BenchmarkRender_Arrays(b *testing.B) {
+	benchmarkRender(b, "{$items[0].name}: {$items[0].amount / 1e18 * $items[0].price} {$items[49]['name']}")
+}
+
+func BenchmarkRender_Mixed(b *testing.B) {
+	benchmarkRender(b, "Transfer {$tx.value / 1e18} ETH from {$tx.from.label} ({$tx.from.address}) "+
+		"to {$tx.to.label} ({$tx.to.address}), first item {$items[0].name} worth {round($items[0].price * 2, 1)}")
+}
+
+var benchLargeContext = func() string {
+	items := make([]string, 0, 2000)
+	for i := 0; i < 2000; i++ {
+		items = append(items, fmt.Sprintf(`{"name": "token%d", "amount": "%d000000000000000000", "price": %d.25, "tags": ["a", "b", "c"]}`, i, i+1, i+1))
+	}
+	return `{"items": [` + strings.Join(items, ",") + `], "tx": {"hash": "0xabc", "value": "8912239900000000000",
+		"from": {"address": "0x1", "label": "alice"}, "to": {"address": "0x2", "label": "bob"}}}`
+}()
+
+// many fragments over a large context
+func BenchmarkRender_LargeContext(b *testing.B) {
+	text := strings.Builder{}
+	for i := 0; i < 30; i++ {
+		text.WriteString(fmt.Sprintf("{$tx.from.label} {$items[%d].name} {$items[%d].amount / 1e18} ", i*60, i*60))
+	}
+	tp, err := NewTemplate(text.String(), nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := tp.Render(benchLargeContext); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package go_template
+
+import (
+	"container/list"
+	"sync"
+)
+
+// DefaultCacheSize is the template cache size of new engines
+const DefaultCacheSize = 1024
+
+// TemplateCache is a bounded LRU of parsed templates, safe for concurrent use
+type TemplateCache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[cacheKey]*list.Element
+	lru     *list.List // most recently used first
+	stats   CacheStats
+}
+
+// CacheStats counts lookups of a TemplateCache
+type CacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Len       int
+}
+
+type cacheKey struct {
+	text   string
+	config string // printed TemplateConfig
+}
+
+type cacheEntry struct {
+	key      cacheKey
+	template *Template
+}
+
+// NewTemplateCache creates a cache holding at most size templates
+func NewTemplateCache(size int) *TemplateCache {
+	return &TemplateCache{
+		size:    size,
+		entries: map[cacheKey]*list.Element{},
+		lru:     list.New(),
+	}
+}
+
+// get returns the cached template or the one created by parse, errors are not cached
+func (c *TemplateCache) get(key cacheKey, parse func() (*Template, error)) (*Template, error) {
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(e)
+		c.stats.Hits++
+		c.mu.Unlock()
+		return e.Value.(*cacheEntry).template, nil
+	}
+	c.stats.Misses++
+	c.mu.Unlock()
+
+	// parse without the lock, concurrent misses of the same key parse twice
+	t, err := parse()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(e)
+		return e.Value.(*cacheEntry).template, nil
+	}
+	c.entries[key] = c.lru.PushFront(&cacheEntry{key: key, template: t})
+	for c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+		c.stats.Evictions++
+	}
+	return t, nil
+}
+
+// Stats returns the lookup statistics
+func (c *TemplateCache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Len = c.lru.Len()
+	return stats
+}
+
+// Purge drops all cached templates, statistics are kept
+func (c *TemplateCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = map[cacheKey]*list.Element{}
+	c.lru.Init()
+}
@@ -0,0 +1,84 @@
+package go_template
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestEngine_Get(t *testing.T) {
+	engine := NewTemplateEngine()
+	a, err := engine.Get("{$a}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := engine.Get("{$a}")
+	if a != b {
+		t.Error("expect cached template")
+	}
+	c, _ := engine.GetWithConfig("{$a}", &TemplateConfig{TimeOffset: 8, TimeFormat: "2006"})
+	if c == a || c.TemplateConfig.TimeOffset != 8 {
+		t.Error("expect template per config")
+	}
+	if _, err := engine.Get("{1 +}"); err == nil {
+		t.Error("expect syntax error")
+	}
+	stats := engine.Cache.Stats()
+	if stats.Hits != 1 || stats.Misses != 3 || stats.Len != 2 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	res, err := b.Render(`{"a": "x"}`)
+	if err != nil || res != "x" {
+		t.Errorf("expect x, got %s %v", res, err)
+	}
+}
+
+func TestTemplateCache_Evict(t *testing.T) {
+	engine := NewTemplateEngine()
+	engine.Cache = NewTemplateCache(2)
+	a, _ := engine.Get("a")
+	_, _ = engine.Get("b")
+	// a is used more recently than b
+	_, _ = engine.Get("a")
+	_, _ = engine.Get("c")
+	if got, _ := engine.Get("a"); got != a {
+		t.Error("expect a kept")
+	}
+	stats := engine.Cache.Stats()
+	if stats.Evictions != 1 || stats.Len != 2 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	_, _ = engine.Get("b")
+	if stats := engine.Cache.Stats(); stats.Misses != 4 {
+		t.Errorf("expect b evicted, got %+v", stats)
+	}
+
+	engine.Cache.Purge()
+	if stats := engine.Cache.Stats(); stats.Len != 0 {
+		t.Errorf("expect empty cache, got %+v", stats)
+	}
+}
+
+func TestTemplateCache_Concurrent(t *testing.T) {
+	engine := NewTemplateEngine()
+	engine.Cache = NewTemplateCache(8)
+	wg := sync.WaitGroup{}
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tp, err := engine.Get(fmt.Sprintf("{$a + %d}", i%10))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if res, err := tp.Render(`{"a": 1}`); err != nil || res != fmt.Sprint(1+i%10) {
+				t.Errorf("unexpected %s %v", res, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if stats := engine.Cache.Stats(); stats.Hits+stats.Misses != 50 || stats.Len > 8 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
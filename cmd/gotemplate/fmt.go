@@ -0,0 +1,62 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/CoinSummer/go-template/format"
+)
+
+func runFmt(args []string, _ io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	list := flags.Bool("l", false, "list files whose formatting differs, exit non-zero if any")
+	write := flags.Bool("w", false, "write result to the source file instead of stdout")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: gotemplate fmt [flags] TEMPLATE_FILE...")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	code := 0
+	for _, path := range flags.Args() {
+		text, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(stderr, "failed read template: %s\n", err)
+			code = 1
+			continue
+		}
+		formatted, err := format.Source(string(text))
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %s\n", path, err)
+			code = 1
+			continue
+		}
+		changed := formatted != string(text)
+		switch {
+		case *list:
+			if changed {
+				fmt.Fprintln(stdout, path)
+				code = 1
+			}
+		case *write:
+			if changed {
+				if err := os.WriteFile(path, []byte(formatted), 0644); err != nil {
+					fmt.Fprintf(stderr, "failed write template: %s\n", err)
+					code = 1
+				}
+			}
+		default:
+			_, _ = io.WriteString(stdout, formatted)
+		}
+	}
+	return code
+}
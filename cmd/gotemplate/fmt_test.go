@@ -0,0 +1,34 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestFmtCommand(t *testing.T) {
+	path := writeFile(t, "t.tpl", "a { $a+1 }")
+	var stdout, stderr bytes.Buffer
+
+	if code := run([]string{"fmt", path}, nil, &stdout, &stderr); code != 0 || stdout.String() != "a {$a + 1}" {
+		t.Errorf("unexpected result %d: %s %s", code, stdout.String(), stderr.String())
+	}
+
+	stdout.Reset()
+	if code := run([]string{"fmt", "-l", path}, nil, &stdout, &stderr); code != 1 || stdout.String() != path+"\n" {
+		t.Errorf("unexpected result %d: %s", code, stdout.String())
+	}
+
+	if code := run([]string{"fmt", "-w", path}, nil, &stdout, &stderr); code != 0 {
+		t.Errorf("unexpected result %d: %s", code, stderr.String())
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != "a {$a + 1}" {
+		t.Errorf("unexpected file content: %s", data)
+	}
+
+	stdout.Reset()
+	if code := run([]string{"fmt", "-l", path}, nil, &stdout, &stderr); code != 0 || stdout.Len() != 0 {
+		t.Errorf("unexpected result %d: %s", code, stdout.String())
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	gt "github.com/CoinSummer/go-template"
+	"github.com/CoinSummer/go-template/lint"
+)
+
+func runLint(args []string, _ io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	asJSON := flags.Bool("json", false, "print diagnostics as a JSON array")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: gotemplate lint [flags] TEMPLATE_FILE...")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	engine := gt.NewTemplateEngine()
+	diagnostics := []lint.Diagnostic{}
+	for _, path := range flags.Args() {
+		text, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(stderr, "failed read template: %s\n", err)
+			return 1
+		}
+		for _, d := range lint.Lint(string(text), engine) {
+			d.File = path
+			diagnostics = append(diagnostics, d)
+		}
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(diagnostics); err != nil {
+			fmt.Fprintf(stderr, "failed write diagnostics: %s\n", err)
+			return 1
+		}
+	} else {
+		for _, d := range diagnostics {
+			fmt.Fprintln(stdout, d)
+		}
+	}
+	for _, d := range diagnostics {
+		if d.Severity == lint.SeverityError {
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/CoinSummer/go-template/lint"
+)
+
+func TestLintCommand(t *testing.T) {
+	ok := writeFile(t, "ok.tpl", "{$a.b + 1}")
+	bad := writeFile(t, "bad.tpl", "{roud($a)}")
+	var stdout, stderr bytes.Buffer
+
+	if code := run([]string{"lint", ok}, nil, &stdout, &stderr); code != 0 || stdout.Len() != 0 {
+		t.Errorf("unexpected result %d: %s", code, stdout.String())
+	}
+
+	code := run([]string{"lint", ok, bad}, nil, &stdout, &stderr)
+	if code != 1 || !strings.HasPrefix(stdout.String(), bad+":1:2: error: unknown function: roud") {
+		t.Errorf("unexpected result %d: %s", code, stdout.String())
+	}
+
+	stdout.Reset()
+	run([]string{"lint", "-json", bad}, nil, &stdout, &stderr)
+	var diagnostics []lint.Diagnostic
+	if err := json.Unmarshal(stdout.Bytes(), &diagnostics); err != nil {
+		t.Fatal(err)
+	}
+	if len(diagnostics) != 1 || diagnostics[0].File != bad || diagnostics[0].Rule != lint.RuleUnknownFunction {
+		t.Errorf("unexpected diagnostics: %+v", diagnostics)
+	}
+}
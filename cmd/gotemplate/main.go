@@ -0,0 +1,53 @@
+// Command gotemplate renders go-template templates from the command line.
+//
+//	gotemplate render [flags] TEMPLATE_FILE
+//	gotemplate repl [flags]
+//	gotemplate lint [flags] TEMPLATE_FILE...
+//	gotemplate fmt [flags] TEMPLATE_FILE...
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+type command struct {
+	name  string
+	usage string
+	run   func(args []string, stdin io.Reader, stdout, stderr io.Writer) int
+}
+
+var commands = []command{
+	{"render", "render a template against a JSON/YAML context", runRender},
+	{"repl", "evaluate expressions interactively", runRepl},
+	{"lint", "check templates for mistakes", runLint},
+	{"fmt", "format templates in canonical form", runFmt},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return 2
+	}
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			return cmd.run(args[1:], stdin, stdout, stderr)
+		}
+	}
+	fmt.Fprintf(stderr, "unknown command: %s\n", args[0])
+	usage(stderr)
+	return 2
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: gotemplate <command> [flags] [args]")
+	fmt.Fprintln(w, "commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-8s %s\n", cmd.name, cmd.usage)
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	gt "github.com/CoinSummer/go-template"
+	"gopkg.in/yaml.v3"
+)
+
+const defaultTimeFormat = "2006-01-02 15:04:05Z07:00"
+
+func runRender(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("render", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	contextFile := flags.String("context", "-", "JSON or YAML context file, - for stdin")
+	offset := flags.Int("offset", 0, "time offset in hours, TemplateConfig.TimeOffset")
+	timeFormat := flags.String("time-format", defaultTimeFormat, "time format, TemplateConfig.TimeFormat")
+	strict := flags.Bool("strict", false, "fail without output on the first fragment failing to evaluate")
+	output := flags.String("o", "-", "output file, - for stdout")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: gotemplate render [flags] TEMPLATE_FILE")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	text, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "failed read template: %s\n", err)
+		return 1
+	}
+	ctx, err := readContext(*contextFile, stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed read context: %s\n", err)
+		return 1
+	}
+
+	config := &gt.TemplateConfig{
+		TimeOffset: *offset,
+		TimeFormat: *timeFormat,
+		Strict:     *strict,
+	}
+	engine := gt.NewTemplateEngine()
+	engine.Logger = &stderrLogger{w: stderr}
+	tp, err := gt.NewTemplateWithConfig(string(text), engine, config)
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %s\n", flags.Arg(0), err)
+		return 1
+	}
+	report, err := tp.RenderDetailedWithConfig(ctx, config)
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %s\n", flags.Arg(0), err)
+		return 1
+	}
+
+	if *output == "-" {
+		_, err = io.WriteString(stdout, report.Output)
+	} else {
+		err = os.WriteFile(*output, []byte(report.Output), 0644)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "failed write output: %s\n", err)
+		return 1
+	}
+	// failed fragments are written back as is and warned about by the logger
+	if len(report.Failed()) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// readContext reads a JSON or YAML context and returns it as JSON
+func readContext(path string, stdin io.Reader) (string, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(string(data)) == "" {
+		return "{}", nil
+	}
+	// keep JSON as is, converting it through YAML loses number precision
+	if json.Valid(data) {
+		return string(data), nil
+	}
+	var value interface{}
+	if err := yaml.Unmarshal(data, &value); err != nil {
+		return "", err
+	}
+	j, err := json.Marshal(jsonCompatible(value))
+	if err != nil {
+		return "", err
+	}
+	return string(j), nil
+}
+
+// jsonCompatible converts YAML maps with non string keys and times to JSON compatible values
+func jsonCompatible(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = jsonCompatible(item)
+		}
+		return v
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[fmt.Sprint(key)] = jsonCompatible(item)
+		}
+		return m
+	case []interface{}:
+		for i, item := range v {
+			v[i] = jsonCompatible(item)
+		}
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	default:
+		return v
+	}
+}
+
+// stderrLogger writes fragment failures as key=value lines
+type stderrLogger struct {
+	w io.Writer
+}
+
+func (l *stderrLogger) Warn(msg string, args ...interface{}) {
+	line := strings.Builder{}
+	line.WriteString("warn: " + msg)
+	for i := 0; i+1 < len(args); i += 2 {
+		line.WriteString(fmt.Sprintf(" %v=%q", args[i], fmt.Sprint(args[i+1])))
+	}
+	fmt.Fprintln(l.w, line.String())
+}
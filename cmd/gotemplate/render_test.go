@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRender(t *testing.T) {
+	tpl := writeFile(t, "t.tpl", "{$a.b * 1000} {$a.c}")
+	var stdout, stderr bytes.Buffer
+
+	code := run([]string{"render", tpl}, strings.NewReader(`{"a": {"b": 1}}`), &stdout, &stderr)
+	if code != 1 || stdout.String() != "1,000 {$a.c}" {
+		t.Errorf("unexpected result %d: %s %s", code, stdout.String(), stderr.String())
+	}
+	if !strings.Contains(stderr.String(), "unknown variable") {
+		t.Errorf("expect warning, got %s", stderr.String())
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	ctx := writeFile(t, "ctx.yaml", "a:\n  b: 1\n  c: x\n")
+	out := filepath.Join(t.TempDir(), "out.txt")
+	code = run([]string{"render", "-context", ctx, "-o", out, tpl}, nil, &stdout, &stderr)
+	data, _ := os.ReadFile(out)
+	if code != 0 || string(data) != "1,000 x" {
+		t.Errorf("unexpected result %d: %s %s", code, data, stderr.String())
+	}
+
+	stderr.Reset()
+	code = run([]string{"render", "-strict", tpl}, strings.NewReader(`{"a": {"b": 1}}`), &stdout, &stderr)
+	if code != 1 || !strings.Contains(stderr.String(), "1:19: unknown variable") {
+		t.Errorf("expect strict failure, got %d: %s", code, stderr.String())
+	}
+}
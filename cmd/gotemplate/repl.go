@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	gt "github.com/CoinSummer/go-template"
+)
+
+const replHelp = `enter an expression to evaluate it, or a command:
+  :funcs         list registered functions
+  :ops           list registered operators
+  :ctx           print the context
+  :load FILE     load a JSON/YAML context file
+  :help          print this help
+  :quit          exit`
+
+func runRepl(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("repl", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	contextFile := flags.String("context", "", "JSON or YAML context file")
+	offset := flags.Int("offset", 0, "time offset in hours, TemplateConfig.TimeOffset")
+	timeFormat := flags.String("time-format", defaultTimeFormat, "time format, TemplateConfig.TimeFormat")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: gotemplate repl [flags]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	r := &repl{
+		engine: gt.NewTemplateEngine(),
+		config: &gt.TemplateConfig{TimeOffset: *offset, TimeFormat: *timeFormat},
+		ctx:    "{}",
+		out:    stdout,
+	}
+	if *contextFile != "" {
+		if err := r.load(*contextFile); err != nil {
+			fmt.Fprintf(stderr, "failed read context: %s\n", err)
+			return 1
+		}
+	}
+
+	scanner := bufio.NewScanner(stdin)
+	for {
+		fmt.Fprint(stdout, "> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(stdout)
+			break
+		}
+		if !r.exec(strings.TrimSpace(scanner.Text())) {
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintf(stderr, "failed read input: %s\n", err)
+		return 1
+	}
+	return 0
+}
+
+type repl struct {
+	engine *gt.TemplateEngine
+	config *gt.TemplateConfig
+	ctx    string
+	out    io.Writer
+}
+
+func (r *repl) load(path string) error {
+	// stdin is the input of the repl
+	if path == "-" {
+		return errors.New("can't read context from stdin, use a file")
+	}
+	ctx, err := readContext(path, nil)
+	if err != nil {
+		return err
+	}
+	r.ctx = ctx
+	return nil
+}
+
+// exec runs a line, returns false to exit
+func (r *repl) exec(line string) bool {
+	command, arg, _ := strings.Cut(line, " ")
+	switch command {
+	case "":
+	case ":quit", ":q":
+		return false
+	case ":help":
+		fmt.Fprintln(r.out, replHelp)
+	case ":funcs":
+		r.listFuncs()
+	case ":ops":
+		r.listOperators()
+	case ":ctx":
+		fmt.Fprintln(r.out, r.ctx)
+	case ":load":
+		if err := r.load(strings.TrimSpace(arg)); err != nil {
+			fmt.Fprintf(r.out, "error: %s\n", err)
+		}
+	default:
+		if strings.HasPrefix(command, ":") {
+			fmt.Fprintf(r.out, "unknown command %s, try :help\n", command)
+			return true
+		}
+		r.eval(line)
+	}
+	return true
+}
+
+func (r *repl) eval(expr string) {
+	f, err := gt.NewExprFragment(expr, r.engine.OperatorsMgr, r.engine.FnMgr)
+	if err == nil {
+		err = f.Validate()
+	}
+	if err != nil {
+		r.printError(err)
+		return
+	}
+	f.Ctx = r.ctx
+	value, err := f.EvalContent(expr, r.config)
+	if err != nil {
+		r.printError(err)
+		return
+	}
+	text, err := gt.FormatValue(value)
+	if err != nil {
+		r.printError(err)
+		return
+	}
+	fmt.Fprintf(r.out, "value:     %v\n", value)
+	fmt.Fprintf(r.out, "type:      %T\n", value)
+	fmt.Fprintf(r.out, "formatted: %s\n", text)
+}
+
+// printError points at the failing part of the expression when known
+func (r *repl) printError(err error) {
+	var te *gt.TemplateError
+	if errors.As(err, &te) && te.Offset >= 0 {
+		// align with the prompt
+		fmt.Fprintf(r.out, "  %s^\n", strings.Repeat(" ", te.Offset))
+		fmt.Fprintf(r.out, "%s: %s\n", te.Kind, te.Message)
+		return
+	}
+	fmt.Fprintf(r.out, "error: %s\n", err)
+}
+
+func (r *repl) listFuncs() {
+	for _, name := range r.engine.FnMgr.Names() {
+		sig := r.engine.FnMgr.GetSignature(name)
+		if sig == nil {
+			fmt.Fprintf(r.out, "%s(...)\n", name)
+			continue
+		}
+		args := make([]string, 0, len(sig.Args))
+		for i := 0; i < len(sig.Args); i++ {
+			arg := string(sig.ArgKind(i))
+			if i >= sig.MinArgs {
+				arg += "?"
+			}
+			args = append(args, arg)
+		}
+		if sig.MaxArgs == gt.Variadic {
+			args = append(args, "...")
+		}
+		fmt.Fprintf(r.out, "%s(%s)\n", name, strings.Join(args, ", "))
+	}
+}
+
+func (r *repl) listOperators() {
+	fmt.Fprintln(r.out, strings.Join(r.engine.OperatorsMgr.Names(), " "))
+}
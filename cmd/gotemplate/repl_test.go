@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRepl(t *testing.T) {
+	ctx := writeFile(t, "ctx.json", `{"a": {"b": 1234.5678}}`)
+	input := strings.Join([]string{
+		"$a.b * 1000",
+		"1 + $a.c",
+		":funcs",
+		":ops",
+		":quit",
+	}, "\n")
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"repl", "-context", ctx}, strings.NewReader(input), &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("unexpected exit %d: %s", code, stderr.String())
+	}
+	expect := []string{
+		"value:     1234567.8\ntype:      decimal.Decimal\nformatted: 1,234,567.8\n",
+		"         ^\nunknown variable: text c not found in {\"b\":1234.5678}\n",
+		"duration(string)\nformatTime(time, number?, string?)\nround(number, number)\ntime(time)\ntimezone(time, number?, string?)\n",
+		"* + - /\n",
+	}
+	for _, s := range expect {
+		if !strings.Contains(stdout.String(), s) {
+			t.Errorf("expect output to contain %q, got %s", s, stdout.String())
+		}
+	}
+}
+
+func TestReplStdinContext(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"repl", "-context", "-"}, strings.NewReader(""), &stdout, &stderr); code != 1 || !strings.Contains(stderr.String(), "stdin") {
+		t.Errorf("expect stdin context rejected, got %d: %s", code, stderr.String())
+	}
+	stdout.Reset()
+	if code := run([]string{"repl"}, strings.NewReader(":load -\n:quit"), &stdout, &stderr); code != 0 || !strings.Contains(stdout.String(), "stdin") {
+		t.Errorf("expect :load - rejected, got %d: %s", code, stdout.String())
+	}
+}
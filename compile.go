@@ -0,0 +1,410 @@
+package go_template
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strconv"
+
+	"github.com/shopspring/decimal"
+	"github.com/tidwall/gjson"
+)
+
+// evalState is what one evaluation reads, fragments are not modified by
+// renders so a template can be rendered concurrently
+type evalState struct {
+	ctx    string // json string
+	config *TemplateConfig
+	rctx   context.Context
+
+	steps int
+	depth int
+
+	root      *ctxNode // ctx parsed on first access, shared by the fragments of a render
+	scopes    Scopes
+	resolvers []VariableResolver
+	resolved  map[string]resolvedVariable
+}
+
+func newEvalState(rctx context.Context, ctx string, config *TemplateConfig) *evalState {
+	if config == nil {
+		config = &TemplateConfig{}
+	}
+	return &evalState{ctx: ctx, config: config, rctx: rctx}
+}
+
+// context returns the root of the parsed ctx
+func (s *evalState) context() *ctxNode {
+	if s.root == nil {
+		s.root = &ctxNode{value: gjson.Parse(s.ctx)}
+	}
+	return s.root
+}
+
+// ctxNode is a value of the context, objects and arrays are split into
+// their members on first access so later lookups don't scan the json again.
+// Values of resolvers are Go values, native nodes hold them as is
+type ctxNode struct {
+	value   gjson.Result
+	indexed bool
+	keys    map[string]*ctxNode
+	items   []*ctxNode
+
+	native bool
+	raw    interface{}
+}
+
+// nativeNode wraps a Go value, nil for nil. Values other than maps, slices
+// and the kinds of KindOf are accessed through their json
+func nativeNode(raw interface{}) *ctxNode {
+	if v, ok := raw.(Value); ok {
+		raw = v.Interface()
+	}
+	if raw == nil {
+		return nil
+	}
+	if KindOf(raw) != KindCustom {
+		return &ctxNode{native: true, raw: raw}
+	}
+	jStr, err := json.Marshal(raw)
+	if err != nil {
+		return &ctxNode{native: true, raw: raw}
+	}
+	return &ctxNode{value: gjson.ParseBytes(jStr)}
+}
+
+// goValue converts the node to the Go value expressions work with
+func (c *ctxNode) goValue() interface{} {
+	if c.native {
+		return c.raw
+	}
+	return jsonValue(c.value)
+}
+
+// child returns the member key of an object or the index key of an array,
+// nil if absent or null
+func (c *ctxNode) child(key string) *ctxNode {
+	if c.native {
+		return c.nativeChild(key)
+	}
+	if !c.indexed {
+		c.indexed = true
+		switch {
+		case c.value.IsObject():
+			c.keys = map[string]*ctxNode{}
+			c.value.ForEach(func(k, v gjson.Result) bool {
+				// the first of duplicated keys wins, as in gjson
+				if _, ok := c.keys[k.String()]; !ok {
+					c.keys[k.String()] = &ctxNode{value: v}
+				}
+				return true
+			})
+		case c.value.IsArray():
+			for _, v := range c.value.Array() {
+				c.items = append(c.items, &ctxNode{value: v})
+			}
+		}
+	}
+	var node *ctxNode
+	if c.keys != nil {
+		node = c.keys[key]
+	} else if i, err := strconv.Atoi(key); err == nil && i >= 0 && i < len(c.items) {
+		node = c.items[i]
+	}
+	if node == nil || node.value.Type == gjson.Null {
+		return nil
+	}
+	return node
+}
+
+// nativeChild looks up members of Go maps and slices, memoized in keys
+func (c *ctxNode) nativeChild(key string) *ctxNode {
+	if node, ok := c.keys[key]; ok {
+		return node
+	}
+	var raw interface{}
+	switch v := c.raw.(type) {
+	case map[string]interface{}:
+		raw = v[key]
+	case []interface{}:
+		if i, err := strconv.Atoi(key); err == nil && i >= 0 && i < len(v) {
+			raw = v[i]
+		}
+	}
+	node := nativeNode(raw)
+	if c.keys == nil {
+		c.keys = map[string]*ctxNode{}
+	}
+	c.keys[key] = node
+	return node
+}
+
+// jsonValue converts a json value to Go like gjson.Result.Value, but keeps
+// numbers as json.Number so they don't lose precision in float64
+func jsonValue(r gjson.Result) interface{} {
+	switch {
+	case r.IsObject():
+		m := map[string]interface{}{}
+		r.ForEach(func(k, v gjson.Result) bool {
+			if _, ok := m[k.String()]; !ok {
+				m[k.String()] = jsonValue(v)
+			}
+			return true
+		})
+		return m
+	case r.IsArray():
+		items := []interface{}{}
+		r.ForEach(func(_, v gjson.Result) bool {
+			items = append(items, jsonValue(v))
+			return true
+		})
+		return items
+	case r.Type == gjson.Number:
+		return json.Number(r.Raw)
+	default:
+		return r.Value()
+	}
+}
+
+// evalFunc is a compiled expression
+type evalFunc func(s *evalState) (interface{}, error)
+
+// compile converts IR to closures, literals are converted once here instead
+// of on every eval. Functions and operators are still looked up on eval, so
+// they can be registered after the template is parsed
+func (f *ExprFragment) compile(n *Node) evalFunc {
+	eval := f.compileOp(n)
+	return func(s *evalState) (interface{}, error) {
+		if err := s.enter(n); err != nil {
+			return nil, err
+		}
+		value, err := eval(s)
+		s.depth--
+		return value, err
+	}
+}
+
+func (f *ExprFragment) compileOp(n *Node) evalFunc {
+	switch n.Op {
+	case OpVariable:
+		return f.compileContext(n)
+	case OpIdentifier:
+		// 常量之外不支持变量, 求值时查找, 解析后注册的常量也可使用
+		return func(s *evalState) (interface{}, error) {
+			if f.Consts != nil {
+				if value, ok := f.Consts.Get(n.Name); ok {
+					return f.Decimalize(value), nil
+				}
+			}
+			return n.Name, newTemplateError(ErrKindUnknownVariable, n.Pos, "%s, variables start with $", n.Name)
+		}
+	case OpDot, OpIndex:
+		if contextRooted(n) {
+			return f.compileContext(n)
+		}
+		return f.compileMember(n)
+	case OpBinary:
+		return f.compileBinary(n)
+	case OpCall:
+		return f.compileCall(n)
+	case OpNumber:
+		d, err := decimal.NewFromString(n.Value)
+		if err != nil {
+			return func(s *evalState) (interface{}, error) {
+				return nil, newTemplateError(ErrKindSyntax, n.Pos, "bad number literal %v", n.Value)
+			}
+		}
+		return func(s *evalState) (interface{}, error) {
+			return d, nil
+		}
+	case OpString:
+		return func(s *evalState) (interface{}, error) {
+			return n.Value, nil
+		}
+	case OpBool:
+		b := n.Value == "true"
+		return func(s *evalState) (interface{}, error) {
+			return b, nil
+		}
+	default:
+		// 三目运算符 todo
+		return func(s *evalState) (interface{}, error) {
+			return nil, newTemplateError(ErrKindSyntax, n.Pos, "expr not supported: %s", n.Name)
+		}
+	}
+}
+
+// compileContext compiles an access chain starting from a $variable, it
+// walks the parsed context and only converts the value at the end
+func (f *ExprFragment) compileContext(n *Node) evalFunc {
+	access := f.compileNode(n)
+	return func(s *evalState) (interface{}, error) {
+		node, err := access(s)
+		if err != nil || node == nil {
+			return nil, err
+		}
+		return f.Decimalize(node.goValue()), nil
+	}
+}
+
+// compileNode compiles an access chain to its node in the context, nil for
+// absent [member]
+func (f *ExprFragment) compileNode(n *Node) func(s *evalState) (*ctxNode, error) {
+	switch n.Op {
+	case OpVariable:
+		return func(s *evalState) (*ctxNode, error) {
+			node, err := s.variable(n.Name)
+			if err != nil {
+				return nil, asTemplateError(err, ErrKindUnknownVariable, n.Pos)
+			}
+			if node == nil {
+				return nil, newTemplateError(ErrKindUnknownVariable, n.Pos, "%s", n.Name)
+			}
+			return node, nil
+		}
+	case OpDot:
+		left := f.compileNode(n.Args[0])
+		return func(s *evalState) (*ctxNode, error) {
+			leftNode, err := left(s)
+			if err != nil {
+				return nil, err
+			}
+			var node *ctxNode
+			if leftNode != nil {
+				node = leftNode.child(n.Name)
+			}
+			if node == nil {
+				var leftValue interface{}
+				if leftNode != nil {
+					leftValue = f.Decimalize(leftNode.goValue())
+				}
+				jStr, _ := json.Marshal(leftValue)
+				return nil, newTemplateError(ErrKindUnknownVariable, n.Pos, "text %s not found in %s", n.Name, string(jStr))
+			}
+			return node, nil
+		}
+	default:
+		left, index := f.compileNode(n.Args[0]), f.compileIndexKey(n)
+		return func(s *evalState) (*ctxNode, error) {
+			leftNode, err := left(s)
+			if err != nil {
+				return nil, err
+			}
+			key, err := index(s)
+			if err != nil || leftNode == nil {
+				return nil, err
+			}
+			return leftNode.child(key), nil
+		}
+	}
+}
+
+// compileMember compiles .key or [member] access on values which are not
+// from the context, like results of functions
+func (f *ExprFragment) compileMember(n *Node) evalFunc {
+	left := f.compile(n.Args[0])
+	var index func(s *evalState) (string, error)
+	if n.Op == OpIndex {
+		index = f.compileIndexKey(n)
+	}
+	return func(s *evalState) (interface{}, error) {
+		leftValue, err := left(s)
+		if err != nil {
+			return nil, err
+		}
+		key := n.Name
+		if index != nil {
+			if key, err = index(s); err != nil {
+				return nil, err
+			}
+		}
+		jStr, err := json.Marshal(leftValue)
+		if err != nil {
+			return nil, newTemplateError(ErrKindType, n.Args[0].Pos, "failed marshal member left: %s err: %s", leftValue, err)
+		}
+		value := jsonValue(gjson.GetBytes(jStr, gjsonEscape(key)))
+		if value == nil && n.Op == OpDot {
+			return nil, newTemplateError(ErrKindUnknownVariable, n.Pos, "text %s not found in %s", n.Name, string(jStr))
+		}
+		return f.Decimalize(value), nil
+	}
+}
+
+// compileIndexKey compiles the member of [member] to a key, numbers index arrays
+func (f *ExprFragment) compileIndexKey(n *Node) func(s *evalState) (string, error) {
+	index := f.compile(n.Args[1])
+	return func(s *evalState) (string, error) {
+		memberValue, err := index(s)
+		if err != nil {
+			return "", err
+		}
+		switch m := memberValue.(type) {
+		case decimal.Decimal:
+			return m.BigInt().String(), nil
+		case string:
+			return m, nil
+		default:
+			return "", newTemplateError(ErrKindType, n.Args[1].Pos, "index must be int or string, got: %s", reflect.TypeOf(m))
+		}
+	}
+}
+
+// contextRooted reports whether n is an access chain starting from a $variable
+func contextRooted(n *Node) bool {
+	for n.Op == OpDot || n.Op == OpIndex {
+		n = n.Args[0]
+	}
+	return n.Op == OpVariable
+}
+
+// 二元操作符
+func (f *ExprFragment) compileBinary(n *Node) evalFunc {
+	left, right := f.compile(n.Args[0]), f.compile(n.Args[1])
+	return func(s *evalState) (interface{}, error) {
+		arg1Value, err := left(s)
+		if err != nil {
+			return arg1Value, err
+		}
+		arg2Value, err := right(s)
+		if err != nil {
+			return arg2Value, err
+		}
+		operator := f.OpMgr.GetFunc(n.Name)
+		if operator == nil {
+			return nil, newTemplateError(ErrKindOperator, n.Pos, "operator not found: %s", n.Name)
+		}
+		result, err := operator(arg1Value, arg2Value)
+		if err != nil {
+			return nil, asTemplateError(err, ErrKindOperator, n.Pos)
+		}
+		return result, nil
+	}
+}
+
+func (f *ExprFragment) compileCall(n *Node) evalFunc {
+	funcName := n.Name
+	if funcName == "" {
+		return func(s *evalState) (interface{}, error) {
+			return nil, newTemplateError(ErrKindSyntax, n.Pos, "only named functions can be called")
+		}
+	}
+	args := make([]evalFunc, 0, len(n.Args))
+	for _, arg := range n.Args {
+		args = append(args, f.compile(arg))
+	}
+	return func(s *evalState) (interface{}, error) {
+		fn := f.FnMgr.GetFunc(funcName)
+		if fn == nil {
+			return nil, newTemplateError(ErrKindUnknownFunction, n.Pos, "%s", funcName)
+		}
+		argsValue := make([]interface{}, 0, len(args))
+		for _, arg := range args {
+			argValue, err := arg(s)
+			if err != nil {
+				return nil, err
+			}
+			argsValue = append(argsValue, argValue)
+		}
+		return f.callFunc(n, fn, s.config, argsValue)
+	}
+}
@@ -0,0 +1,78 @@
+package go_template
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestCompile_MissingMember(t *testing.T) {
+	f, _ := NewExprFragment("$a.b.c", NewOperatorsMgr(), NewFnMgr())
+	_, err := f.Eval(`{"a": {"b": {"d": 1}}}`, nil)
+	var te *TemplateError
+	if !errors.As(err, &te) || te.Kind != ErrKindUnknownVariable || te.Offset != 5 {
+		t.Fatalf("expect missing c at offset 5, got %v", err)
+	}
+	res, err := f.Eval(`{"a": {"b": {"c": "x"}}}`, nil)
+	if err != nil || res != "x" {
+		t.Errorf("expect x, got %v %v", res, err)
+	}
+}
+
+func TestTemplate_RenderConcurrent(t *testing.T) {
+	tp, err := NewTemplate("{$a.b * 2} {$c}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wg := sync.WaitGroup{}
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			res, err := tp.Render(fmt.Sprintf(`{"a": {"b": %d}, "c": "%d"}`, i, i))
+			if expect := fmt.Sprintf("%d %d", i*2, i); err != nil || res != expect {
+				t.Errorf("expect %s, got %s %v", expect, res, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestCompile_Member(t *testing.T) {
+	engine := NewTemplateEngine()
+	if err := engine.FnMgr.RegisterFunc("pair", func(_ *TemplateConfig, args []interface{}) (interface{}, error) {
+		return struct {
+			Left  interface{} `json:"left"`
+			Right interface{} `json:"right"`
+		}{args[0], args[1]}, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	tp, err := NewTemplate("{$a[1]} {$a['0']} {$o['1']} {$o.x.y} {pair($o.x.y, 'r').right} {$a[5]}", engine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := tp.Render(`{"a": ["p", "q"], "o": {"1": "one", "x": {"y": "z"}}}`)
+	if expect := "q p one z r {$a[5]}"; err != nil || res != expect {
+		t.Errorf("expect %s, got %s %v", expect, res, err)
+	}
+}
+
+func TestEvalState_Context(t *testing.T) {
+	s := newEvalState(context.Background(), `{"a": [{"b": 1}, null], "c": {"d": "x", "d": "y"}}`, nil)
+	if s.context() != s.context() {
+		t.Fatal("expect context parsed once")
+	}
+	a := s.context().child("a")
+	if a == nil || a.child("0").child("b").value.Int() != 1 {
+		t.Errorf("expect a[0].b = 1")
+	}
+	if a.child("1") != nil || a.child("2") != nil || a.child("x") != nil || s.context().child("z") != nil {
+		t.Errorf("expect null and absent members to be nil")
+	}
+	if d := s.context().child("c").child("d"); d == nil || d.value.String() != "x" {
+		t.Errorf("expect first duplicated key")
+	}
+}
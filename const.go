@@ -0,0 +1,94 @@
+package go_template
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ConstMgr holds constants bare identifiers like WEI resolve to, register
+// them through TemplateEngine.RegisterConstant. It is safe for concurrent use
+type ConstMgr struct {
+	// constants registered on this manager, inherited ones are in parent
+	Consts map[string]interface{}
+
+	mu     sync.RWMutex
+	parent *ConstMgr
+}
+
+func NewConstMgr() *ConstMgr {
+	return &ConstMgr{Consts: map[string]interface{}{}}
+}
+
+// Fork creates a manager whose lookups fall back to c, constants
+// registered on it shadow the ones of c without affecting it
+func (c *ConstMgr) Fork() *ConstMgr {
+	return &ConstMgr{Consts: map[string]interface{}{}, parent: c}
+}
+
+func (c *ConstMgr) register(name string, value interface{}) {
+	if v, ok := value.(Value); ok {
+		value = v.Interface()
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Consts[name] = value
+}
+
+// Get returns the value of the constant, ok is false if it's not registered
+func (c *ConstMgr) Get(name string) (interface{}, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.RLock()
+	value, ok := c.Consts[name]
+	c.mu.RUnlock()
+	if !ok && c.parent != nil {
+		return c.parent.Get(name)
+	}
+	return value, ok
+}
+
+// Names returns the sorted names of all constants, inherited ones included
+func (c *ConstMgr) Names() []string {
+	seen := map[string]bool{}
+	for m := c; m != nil; m = m.parent {
+		m.mu.RLock()
+		for name := range m.Consts {
+			seen[name] = true
+		}
+		m.mu.RUnlock()
+	}
+	return sortedKeys(seen)
+}
+
+// RegisterConstant makes the bare identifier name evaluate to value in
+// templates of the engine, names of functions are rejected. Engines without
+// ConstMgr get one on the first constant
+func (e *TemplateEngine) RegisterConstant(name string, value interface{}) error {
+	if name == "" || strings.HasPrefix(name, "$") {
+		return fmt.Errorf("bad constant name %q, $names are variables", name)
+	}
+	if e.ConstMgr == nil {
+		e.ConstMgr = NewConstMgr()
+	}
+	if e.FnMgr == nil {
+		e.ConstMgr.register(name, value)
+		return nil
+	}
+	return e.FnMgr.registerConstant(e.ConstMgr, name, value)
+}
+
+// registerConstant checks and registers the constant with f locked, RegisterFunc
+// holds the same lock, so a function of the same name can't be registered in between
+func (f *FnMgr) registerConstant(c *ConstMgr, name string, value interface{}) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.consts = c
+	_, own := f.Funcs[name]
+	if own || (f.parent != nil && f.parent.GetFunc(name) != nil) {
+		return fmt.Errorf("constant %s collides with function %s", name, name)
+	}
+	c.register(name, value)
+	return nil
+}
@@ -0,0 +1,108 @@
+package go_template
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestRegisterConstant(t *testing.T) {
+	engine := NewTemplateEngine()
+	if err := engine.RegisterConstant("GWEI", decimal.New(1, 9)); err != nil {
+		t.Fatal(err)
+	}
+	_ = engine.RegisterConstant("BRAND_NAME", StringValue("Acme"))
+	if err := engine.RegisterConstant("round", 1); err == nil {
+		t.Error("expect collision with function round")
+	}
+	if err := engine.RegisterConstant("$a", 1); err == nil {
+		t.Error("expect $a rejected")
+	}
+
+	tp, err := engine.Get("{BRAND_NAME}: {$fee / GWEI} gwei {PI}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, _ := tp.Render(`{"fee": 21000000000000}`)
+	if expect := "Acme: 21,000 gwei {PI}"; res != expect {
+		t.Errorf("expect %s, got %s", expect, res)
+	}
+	consts := tp.Constants()
+	if len(consts) != 3 || consts[0].Name != "BRAND_NAME" || consts[1].Name != "GWEI" || consts[2].Name != "PI" {
+		t.Errorf("unexpected constants: %+v", consts)
+	}
+
+	// constants registered after parsing and on child engines
+	child := engine.Fork()
+	_ = child.RegisterConstant("PI", 3.14)
+	_ = child.RegisterConstant("BRAND_NAME", "Tenant")
+	tp, _ = NewTemplate("{BRAND_NAME} {PI}", child)
+	if res, _ := tp.Render(`{}`); res != "Tenant 3.14" {
+		t.Errorf("unexpected child render %s", res)
+	}
+	if names := child.ConstMgr.Names(); len(names) != 3 {
+		t.Errorf("unexpected names %v", names)
+	}
+	if v, _ := engine.ConstMgr.Get("BRAND_NAME"); v != "Acme" {
+		t.Errorf("parent modified: %v", v)
+	}
+
+	// functions can't be named after constants, inherited ones included
+	fn := func(config *TemplateConfig, args []interface{}) (interface{}, error) { return nil, nil }
+	if err := engine.FnMgr.RegisterFunc("GWEI", fn); err == nil {
+		t.Error("expect collision with constant GWEI")
+	}
+	if err := child.FnMgr.RegisterFuncWithSignature("BRAND_NAME", fn, Signature{}); err == nil {
+		t.Error("expect collision with inherited constant BRAND_NAME")
+	}
+	if err := child.FnMgr.RegisterFunc("brand", fn); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRegisterConstant_NoConstMgr(t *testing.T) {
+	engine := &TemplateEngine{FnMgr: NewFnMgr(), OperatorsMgr: NewOperatorsMgr(), Hooks: NewHooks()}
+	tp, _ := NewTemplate("{WEI}", engine)
+	if res, _ := tp.Render("{}"); res != "{WEI}" {
+		t.Errorf("expect raw, got %s", res)
+	}
+	if err := engine.RegisterConstant("WEI", 1); err != nil {
+		t.Fatal(err)
+	}
+	tp, _ = NewTemplate("{WEI}", engine)
+	if res, _ := tp.Render("{}"); res != "1" {
+		t.Errorf("expect 1, got %s", res)
+	}
+	if err := engine.FnMgr.RegisterFunc("WEI", nil); err == nil {
+		t.Error("expect collision with constant WEI")
+	}
+}
+
+func TestRegisterConstant_Concurrent(t *testing.T) {
+	for i := 0; i < 50; i++ {
+		engine := NewTemplateEngine()
+		errs := make(chan error, 2)
+		wg := sync.WaitGroup{}
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			errs <- engine.RegisterConstant("WEI", 1)
+		}()
+		go func() {
+			defer wg.Done()
+			errs <- engine.FnMgr.RegisterFunc("WEI", nil)
+		}()
+		wg.Wait()
+		close(errs)
+		failed := 0
+		for err := range errs {
+			if err != nil {
+				failed++
+			}
+		}
+		if failed != 1 {
+			t.Fatalf("expect exactly one registration rejected, got %d", failed)
+		}
+	}
+}
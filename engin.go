@@ -1,15 +1,100 @@
 package go_template
 
+import "fmt"
+
 type TemplateEngine struct {
 	FnMgr        *FnMgr
 	OperatorsMgr *OperatorsMgr
+	// ConstMgr holds constants of bare identifiers, see RegisterConstant
+	ConstMgr *ConstMgr
+	// Logger receives warnings of failed fragments, silent by default
+	Logger Logger
+	// LogContext adds the whole render context to warnings, may leak user data
+	LogContext bool
+	// Hooks are notified around renders, fragments and function calls
+	Hooks *Hooks
+	// Cache holds templates parsed by Get, nil disables caching
+	Cache *TemplateCache
+	// Resolvers resolve variables absent from the render context, in order,
+	// register them before rendering
+	Resolvers []VariableResolver
 }
 
 func NewTemplateEngine() *TemplateEngine {
 	fm := NewFnMgr()
 	om := NewOperatorsMgr()
+	cm := NewConstMgr()
+	fm.consts = cm
 	return &TemplateEngine{
 		FnMgr:        fm,
 		OperatorsMgr: om,
+		ConstMgr:     cm,
+		Logger:       NopLogger{},
+		Hooks:        NewHooks(),
+		Cache:        NewTemplateCache(DefaultCacheSize),
+	}
+}
+
+// AddHook registers a RenderHook, FragmentHook and/or FuncHook
+func (e *TemplateEngine) AddHook(hook interface{}) bool {
+	return e.Hooks.Add(hook)
+}
+
+// AddResolver appends a resolver for variables absent from the render context
+func (e *TemplateEngine) AddResolver(r VariableResolver) {
+	e.Resolvers = append(e.Resolvers, r)
+}
+
+// Fork creates a child engine, functions, operators and constants registered on it
+// shadow the parent's without affecting it, other lookups fall back to the
+// parent. The child starts with the parent's logger, hooks and resolvers
+// and has its own template cache
+func (e *TemplateEngine) Fork() *TemplateEngine {
+	child := &TemplateEngine{
+		FnMgr:        e.FnMgr.Fork(),
+		OperatorsMgr: e.OperatorsMgr.Fork(),
+		ConstMgr:     e.ConstMgr.Fork(),
+		Logger:       e.Logger,
+		LogContext:   e.LogContext,
+		Hooks:        e.Hooks.fork(),
+		Resolvers:    append([]VariableResolver(nil), e.Resolvers...),
+	}
+	child.FnMgr.consts = child.ConstMgr
+	if e.Cache != nil {
+		child.Cache = NewTemplateCache(e.Cache.size)
+	}
+	return child
+}
+
+// Get returns the template of text parsed with the default config, cached
+// by the engine. Cached templates are shared, don't modify them
+func (e *TemplateEngine) Get(text string) (*Template, error) {
+	return e.GetWithConfig(text, nil)
+}
+
+// GetWithConfig returns the template of text parsed with config, cached by
+// text and config
+func (e *TemplateEngine) GetWithConfig(text string, config *TemplateConfig) (*Template, error) {
+	if config == nil {
+		config = defaultConfig()
+	}
+	key := cacheKey{text: text, config: fmt.Sprintf("%#v", *config)}
+	parse := func() (*Template, error) {
+		// the cached template keeps its own copy of config
+		c := *config
+		c.Capabilities = append([]string(nil), config.Capabilities...)
+		return NewTemplateWithConfig(text, e, &c)
+	}
+	if e.Cache == nil {
+		return parse()
+	}
+	tp, err := e.Cache.get(key, parse)
+	if err != nil {
+		return nil, err
+	}
+	// capabilities may have changed since the template was cached
+	if err := tp.checkCurrentCapabilities(); err != nil {
+		return nil, err
 	}
+	return tp, nil
 }
@@ -0,0 +1,78 @@
+package go_template
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestEngine_Fork(t *testing.T) {
+	parent := NewTemplateEngine()
+	tenant := parent.Fork()
+	if err := tenant.FnMgr.RegisterFuncWithSignature("upper", func(_ *TemplateConfig, args []interface{}) (interface{}, error) {
+		return strings.ToUpper(args[0].(string)), nil
+	}, Signature{MinArgs: 1, MaxArgs: 1, Args: []ArgKind{ArgString}}); err != nil {
+		t.Fatal(err)
+	}
+	// shadow round without signature
+	if err := tenant.FnMgr.RegisterFunc("round", func(_ *TemplateConfig, args []interface{}) (interface{}, error) {
+		return "rounded", nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	tenant.OperatorsMgr.RegisterFunc("%", func(a, b interface{}) (interface{}, error) {
+		return "mod", nil
+	})
+
+	tp, err := NewTemplate("{upper($a)} {round(1)} {timezone(0)} {1 % 2} {1 + 2}", tenant)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := tp.Render(`{"a": "x"}`)
+	if expect := "X rounded 1970-01-01 00:00:00Z mod 3"; err != nil || res != expect {
+		t.Errorf("expect %s, got %s %v", expect, res, err)
+	}
+
+	if _, err := NewTemplate("{upper($a)}", parent); err == nil {
+		t.Error("expect upper unknown to parent")
+	}
+	if _, err := NewTemplate("{round(1)}", parent); err == nil {
+		t.Error("expect round signature kept in parent")
+	}
+	if parent.OperatorsMgr.GetFunc("%") != nil {
+		t.Error("expect % unknown to parent")
+	}
+	if names := strings.Join(tenant.FnMgr.Names(), ","); names != "duration,formatTime,round,time,timezone,upper" {
+		t.Errorf("unexpected names %s", names)
+	}
+}
+
+func TestEngine_ForkConcurrentRegister(t *testing.T) {
+	parent := NewTemplateEngine()
+	child := parent.Fork()
+	tp, err := NewTemplate("{round($a, 1)}", child)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wg := sync.WaitGroup{}
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			if err := parent.FnMgr.RegisterFunc(fmt.Sprintf("fn%d", i), nil); err != nil {
+				t.Error(err)
+			}
+			if err := child.FnMgr.RegisterFunc(fmt.Sprintf("child%d", i), nil); err != nil {
+				t.Error(err)
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			if res, err := tp.Render(`{"a": 1.25}`); err != nil || res != "1.3" {
+				t.Errorf("unexpected %s %v", res, err)
+			}
+		}()
+	}
+	wg.Wait()
+}
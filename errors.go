@@ -0,0 +1,107 @@
+package go_template
+
+import (
+	"errors"
+	"fmt"
+
+	astParser "github.com/dop251/goja/parser"
+)
+
+// ErrorKind classifies template errors
+type ErrorKind string
+
+const (
+	ErrKindSyntax          ErrorKind = "syntax error"
+	ErrKindUnknownVariable ErrorKind = "unknown variable"
+	ErrKindUnknownFunction ErrorKind = "unknown function"
+	ErrKindType            ErrorKind = "type error"
+	ErrKindOperator        ErrorKind = "operator error"
+	ErrKindFunction        ErrorKind = "function error"
+	ErrKindLimit           ErrorKind = "limit exceeded"
+	ErrKindNotAllowed      ErrorKind = "function not allowed"
+)
+
+// TemplateError is returned for errors located in a template expression,
+// use errors.As to retrieve it
+type TemplateError struct {
+	Kind     ErrorKind
+	Message  string
+	Fragment int    // index of the fragment in the template, -1 if unknown
+	Line     int    // 1-based line in the template, 0 if unknown
+	Column   int    // 1-based column in the template, 0 if unknown
+	Source   string // expression source, without {}
+	Offset   int    // byte offset of the error in Source, -1 if unknown
+	Err      error  // underlying error, if any
+}
+
+func (e *TemplateError) Error() string {
+	msg := fmt.Sprintf("%s: %s", e.Kind, e.Message)
+	if e.Line > 0 {
+		msg = fmt.Sprintf("%d:%d: %s", e.Line, e.Column, msg)
+	}
+	if e.Source != "" {
+		msg += fmt.Sprintf(" in {%s}", e.Source)
+	}
+	return msg
+}
+
+func (e *TemplateError) Unwrap() error {
+	return e.Err
+}
+
+// newTemplateError creates an error at byte offset pos of the expression, -1 if unknown
+func newTemplateError(kind ErrorKind, pos int, format string, a ...interface{}) *TemplateError {
+	return &TemplateError{
+		Kind:     kind,
+		Message:  fmt.Sprintf(format, a...),
+		Fragment: -1,
+		Offset:   pos,
+	}
+}
+
+// asTemplateError returns the TemplateError in err's chain, wrapping err
+// with the given kind if there is none
+func asTemplateError(err error, kind ErrorKind, pos int) *TemplateError {
+	var te *TemplateError
+	if errors.As(err, &te) {
+		if te.Offset < 0 {
+			te.Offset = pos
+		}
+		return te
+	}
+	te = newTemplateError(kind, pos, "%s", err)
+	te.Err = err
+	return te
+}
+
+// syntaxError converts goja parser errors to a TemplateError
+func syntaxError(text string, err error) *TemplateError {
+	te := &TemplateError{
+		Kind:     ErrKindSyntax,
+		Message:  err.Error(),
+		Fragment: -1,
+		Offset:   -1,
+		Source:   text,
+		Err:      err,
+	}
+	var list astParser.ErrorList
+	if errors.As(err, &list) && len(list) > 0 {
+		te.Message = list[0].Message
+		te.Offset = offsetOf(text, list[0].Position.Line, list[0].Position.Column)
+	}
+	return te
+}
+
+// locate fills the position of the error in the template
+func (e *TemplateError) locate(template string, fragment int, span Span) {
+	e.Fragment = fragment
+	// skip the opening {
+	offset := span.Start + 1
+	if e.Offset > 0 {
+		offset += e.Offset
+	}
+	if offset > len(template) {
+		offset = len(template)
+	}
+	e.Line, e.Column = lineColumn(template, offset)
+}
@@ -0,0 +1,103 @@
+package go_template
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSyntaxErrorPosition(t *testing.T) {
+	_, err := NewTemplate("line1\nxx {$a + 1a} yy", nil)
+	var te *TemplateError
+	if !errors.As(err, &te) {
+		t.Fatalf("expect TemplateError, got %v", err)
+	}
+	if te.Kind != ErrKindSyntax || te.Fragment != 1 || te.Source != "$a + 1a" {
+		t.Errorf("unexpected error: %+v", te)
+	}
+	if te.Line != 2 || te.Column != 10 {
+		t.Errorf("expect 2:10, got %d:%d", te.Line, te.Column)
+	}
+}
+
+func TestRenderErrorKinds(t *testing.T) {
+	cases := map[string]ErrorKind{
+		"{$a.c}":         ErrKindUnknownVariable,
+		"{a}":            ErrKindUnknownVariable,
+		"{round($s, 1)}": ErrKindType,
+		"{$a.b % 2}":     ErrKindOperator,
+	}
+	for text, kind := range cases {
+		tp, err := NewTemplate("x "+text, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		report, err := tp.RenderDetailed(`{"a": {"b": 333}, "s": "x"}`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var te *TemplateError
+		if !errors.As(report.Fragments[1].Err, &te) {
+			t.Fatalf("%s: expect TemplateError, got %v", text, report.Fragments[1].Err)
+		}
+		if te.Kind != kind || te.Fragment != 1 || te.Line != 1 {
+			t.Errorf("%s: unexpected error: %+v", text, te)
+		}
+	}
+}
+
+func TestErrorColumn(t *testing.T) {
+	tp, _ := NewTemplate("ab {1 + $a.c}", nil)
+	report, _ := tp.RenderDetailed(`{"a": {}}`)
+	var te *TemplateError
+	if !errors.As(report.Fragments[1].Err, &te) {
+		t.Fatal(report.Fragments[1].Err)
+	}
+	if te.Column != 12 {
+		t.Errorf("expect column 12, got %d", te.Column)
+	}
+}
+
+func TestErrorMessage(t *testing.T) {
+	tp, _ := NewTemplate("x {$missing} {a}", nil)
+	report, _ := tp.RenderDetailed(`{}`)
+	for i, msg := range map[int]string{
+		1: "1:4: unknown variable: missing in {$missing}",
+		3: "1:15: unknown variable: a, variables start with $ in {a}",
+	} {
+		if err := report.Fragments[i].Err; err == nil || err.Error() != msg {
+			t.Errorf("expect %s, got %v", msg, err)
+		}
+	}
+}
+
+func TestParseFunctionErrors(t *testing.T) {
+	cases := map[string]string{
+		"x {roud($a, 2)}":           "1:4: unknown function: roud in {roud($a, 2)}",
+		"x {round($a)}":             "1:4: type error: round expects 2 args, got 1 in {round($a)}",
+		"x {timezone($a, 8, 1, 2)}": "1:4: type error: timezone expects 1 to 3 args, got 4 in {timezone($a, 8, 1, 2)}",
+		"x {1 + round('a', 1)}":     "1:14: type error: round arg0 must be number, got string in {1 + round('a', 1)}",
+		"x {timezone($a, '8')}":     "1:17: type error: timezone arg1 must be number, got string in {timezone($a, '8')}",
+	}
+	for text, msg := range cases {
+		_, err := NewTemplate(text, nil)
+		if err == nil || err.Error() != msg {
+			t.Errorf("%s: expect %s, got %v", text, msg, err)
+		}
+	}
+
+	engine := NewTemplateEngine()
+	if err := engine.FnMgr.RegisterFuncWithSignature("join", func(_ *TemplateConfig, args []interface{}) (interface{}, error) {
+		return len(args), nil
+	}, Signature{MinArgs: 1, MaxArgs: Variadic, Args: []ArgKind{ArgString}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewTemplate("{join('a', 'b', 'c')}", engine); err != nil {
+		t.Error(err)
+	}
+	if _, err := NewTemplate("{join()}", engine); err == nil {
+		t.Error("expect arity error")
+	}
+	if _, err := NewTemplate("{join('a', 1)}", engine); err == nil {
+		t.Error("expect type error")
+	}
+}
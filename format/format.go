@@ -0,0 +1,220 @@
+// Package format prints templates in canonical form: expressions are
+// re-printed with normalized spacing and double quoted strings, plain text
+// is kept byte for byte.
+package format
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+
+	gt "github.com/CoinSummer/go-template"
+	"github.com/dop251/goja/ast"
+	astParser "github.com/dop251/goja/parser"
+	"github.com/dop251/goja/token"
+)
+
+// Source formats template source, expressions which fail to parse are
+// returned in the error and kept as is in the output
+func Source(text string) (string, error) {
+	out := strings.Builder{}
+	var firstErr error
+	for _, segment := range gt.ScanTemplate(text) {
+		if !segment.Expr || !segment.Closed {
+			out.WriteString(text[segment.Span.Start:segment.Span.End])
+			continue
+		}
+		expr, err := Expr(segment.Content)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			out.WriteString(text[segment.Span.Start:segment.Span.End])
+			continue
+		}
+		out.WriteString("{" + expr + "}")
+	}
+	return out.String(), firstErr
+}
+
+// Expr formats a single expression, without {}
+func Expr(src string) (string, error) {
+	program, err := astParser.ParseFile(nil, "", src, 0)
+	if err != nil {
+		return "", err
+	}
+	if len(program.Body) != 1 {
+		return "", fmt.Errorf("only support ONE expr: %s", src)
+	}
+	stmt, ok := program.Body[0].(*ast.ExpressionStatement)
+	if !ok {
+		return "", fmt.Errorf("expr not support: %s", src)
+	}
+	p := &printer{}
+	p.expr(stmt.Expression, precConditional)
+	if p.err != nil {
+		return "", p.err
+	}
+	return p.out.String(), nil
+}
+
+type printer struct {
+	out strings.Builder
+	err error
+}
+
+// binary operator precedence, higher binds tighter
+var precedence = map[token.Token]int{
+	token.COALESCE:             1,
+	token.LOGICAL_OR:           2,
+	token.LOGICAL_AND:          3,
+	token.OR:                   4,
+	token.EXCLUSIVE_OR:         5,
+	token.AND:                  6,
+	token.EQUAL:                7,
+	token.NOT_EQUAL:            7,
+	token.STRICT_EQUAL:         7,
+	token.STRICT_NOT_EQUAL:     7,
+	token.LESS:                 8,
+	token.GREATER:              8,
+	token.LESS_OR_EQUAL:        8,
+	token.GREATER_OR_EQUAL:     8,
+	token.INSTANCEOF:           8,
+	token.IN:                   8,
+	token.SHIFT_LEFT:           9,
+	token.SHIFT_RIGHT:          9,
+	token.UNSIGNED_SHIFT_RIGHT: 9,
+	token.PLUS:                 10,
+	token.MINUS:                10,
+	token.MULTIPLY:             11,
+	token.SLASH:                11,
+	token.REMAINDER:            11,
+	token.EXPONENT:             12,
+}
+
+const (
+	precConditional = -1
+	precUnary       = 13
+	precPrimary     = 14
+)
+
+func exprPrecedence(expr ast.Expression) int {
+	switch expr := expr.(type) {
+	case *ast.BinaryExpression:
+		return precedence[expr.Operator]
+	case *ast.ConditionalExpression:
+		return precConditional
+	case *ast.UnaryExpression:
+		return precUnary
+	default:
+		return precPrimary
+	}
+}
+
+// expr prints expr, parenthesized if it binds looser than min
+func (p *printer) expr(expr ast.Expression, min int) {
+	if exprPrecedence(expr) < min {
+		p.out.WriteString("(")
+		defer p.out.WriteString(")")
+	}
+	switch expr := expr.(type) {
+	case *ast.Identifier:
+		p.out.WriteString(expr.Name.String())
+	case *ast.NumberLiteral:
+		p.out.WriteString(expr.Literal)
+	case *ast.StringLiteral:
+		p.out.WriteString(quote(expr.Value.String()))
+	case *ast.BooleanLiteral:
+		p.out.WriteString(strconv.FormatBool(expr.Value))
+	case *ast.NullLiteral:
+		p.out.WriteString("null")
+	case *ast.DotExpression:
+		p.expr(expr.Left, precPrimary)
+		p.out.WriteString("." + expr.Identifier.Name.String())
+	case *ast.BracketExpression:
+		p.expr(expr.Left, precPrimary)
+		p.out.WriteString("[")
+		p.expr(expr.Member, precConditional)
+		p.out.WriteString("]")
+	case *ast.CallExpression:
+		p.expr(expr.Callee, precPrimary)
+		p.out.WriteString("(")
+		for i, arg := range expr.ArgumentList {
+			if i > 0 {
+				p.out.WriteString(", ")
+			}
+			p.expr(arg, precConditional)
+		}
+		p.out.WriteString(")")
+	case *ast.BinaryExpression:
+		prec := precedence[expr.Operator]
+		// left associative, except **
+		left, right := prec, prec+1
+		if expr.Operator == token.EXPONENT {
+			left, right = prec+1, prec
+		}
+		p.expr(expr.Left, left)
+		p.out.WriteString(" " + expr.Operator.String() + " ")
+		p.expr(expr.Right, right)
+	case *ast.UnaryExpression:
+		if expr.Postfix {
+			p.expr(expr.Operand, precPrimary)
+			p.out.WriteString(expr.Operator.String())
+			return
+		}
+		op := expr.Operator.String()
+		p.out.WriteString(op)
+		operand, nested := expr.Operand.(*ast.UnaryExpression)
+		if (len(op) > 1 && op != "++" && op != "--") || (nested && !operand.Postfix) {
+			// typeof, void, delete, or - -x
+			p.out.WriteString(" ")
+		}
+		p.expr(expr.Operand, precUnary)
+	case *ast.ConditionalExpression:
+		p.expr(expr.Test, precConditional+1)
+		p.out.WriteString(" ? ")
+		p.expr(expr.Consequent, precConditional)
+		p.out.WriteString(" : ")
+		p.expr(expr.Alternate, precConditional)
+	default:
+		if p.err == nil {
+			p.err = fmt.Errorf("expr not supported: %T", expr)
+		}
+	}
+}
+
+// quote double quotes s as a JS string literal, unlike strconv.Quote it
+// never writes Go only escapes like \a or \U
+func quote(s string) string {
+	out := strings.Builder{}
+	out.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"', '\\':
+			out.WriteByte('\\')
+			out.WriteRune(r)
+		case '\n':
+			out.WriteString(`\n`)
+		case '\r':
+			out.WriteString(`\r`)
+		case '\t':
+			out.WriteString(`\t`)
+		default:
+			// line separators end string literals in older JS
+			if unicode.IsPrint(r) && r != '\u2028' && r != '\u2029' {
+				out.WriteRune(r)
+				continue
+			}
+			if r > 0xffff {
+				r1, r2 := utf16.EncodeRune(r)
+				fmt.Fprintf(&out, `\u%04x\u%04x`, r1, r2)
+			} else {
+				fmt.Fprintf(&out, `\u%04x`, r)
+			}
+		}
+	}
+	out.WriteByte('"')
+	return out.String()
+}
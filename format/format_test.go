@@ -0,0 +1,44 @@
+package format
+
+import (
+	"testing"
+)
+
+func TestSource(t *testing.T) {
+	cases := map[string]string{
+		"a  { $a+ $b }  b":               "a  {$a + $b}  b",
+		"{round( $a.b/1e18 ,2)}":         "{round($a.b / 1e18, 2)}",
+		"{$a['b.c'] * (1 + 2)}":          `{$a["b.c"] * (1 + 2)}`,
+		"{(($a * 2)) - (1 - 2)}":         "{$a * 2 - (1 - 2)}",
+		"{1 - 2 - 3}":                    "{1 - 2 - 3}",
+		"{'it\\'s'}":                     `{"it's"}`,
+		`{"\u0007\n\"" + $a}`:            `{"\u0007\n\"" + $a}`,
+		"{'\u2028\U0001F600\U000E0001'}": "{\"\\u2028\U0001F600\\udb40\\udc01\"}",
+		"{$a?1:2}":                       "{$a ? 1 : 2}",
+		"{-$a}":                          "{-$a}",
+		"line1\n{$a}\tline2 {unclosed":   "line1\n{$a}\tline2 {unclosed",
+	}
+	for src, expect := range cases {
+		got, err := Source(src)
+		if err != nil {
+			t.Errorf("%s: %s", src, err)
+		}
+		if got != expect {
+			t.Errorf("format %q: expect %q, got %q", src, expect, got)
+		}
+		again, _ := Source(got)
+		if again != got {
+			t.Errorf("format not stable: %q -> %q", got, again)
+		}
+	}
+}
+
+func TestSourceError(t *testing.T) {
+	got, err := Source("{ $a +1} {1a} { $b }")
+	if err == nil {
+		t.Error("expect error")
+	}
+	if got != "{$a + 1} {1a} {$b}" {
+		t.Errorf("unexpected output %q", got)
+	}
+}
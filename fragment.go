@@ -1,16 +1,18 @@
 package go_template
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"math/big"
 	"reflect"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/dop251/goja/ast"
 	astParser "github.com/dop251/goja/parser"
 	"github.com/shopspring/decimal"
-	"github.com/sirupsen/logrus"
-	"github.com/tidwall/gjson"
 )
 
 type IFragment interface {
@@ -42,11 +44,17 @@ func (p *PlainFragment) RawContent() string {
 // -------------------------------------------------------------
 
 type ExprFragment struct {
-	Content string // without {}
-	Ast     *ast.Program
+	Content string       // without {}
+	Ast     *ast.Program // nil for fragments of precompiled templates
+	Node    *Node        // IR the expression is compiled from
 	Ctx     string
 	OpMgr   *OperatorsMgr
 	FnMgr   *FnMgr
+	Consts  *ConstMgr // nil for no constants
+	Hooks   *Hooks
+
+	compileOnce sync.Once
+	compiled    evalFunc
 }
 
 func NewExprFragment(text string, opMgr *OperatorsMgr, fnMgr *FnMgr) (*ExprFragment, error) {
@@ -57,196 +65,173 @@ func NewExprFragment(text string, opMgr *OperatorsMgr, fnMgr *FnMgr) (*ExprFragm
 	}
 	p, err := astParser.ParseFile(nil, "", text, 0)
 	if err != nil {
-		return nil, ErrFMsg("failed parse expr: %s, err: %s", text, err)
+		return nil, syntaxError(text, err)
 	}
 	f.Ast = p
 	if len(p.Body) != 1 {
-		return nil, ErrFMsg(" < only support ONE expr: %s > ", text)
+		return nil, f.withSource(newTemplateError(ErrKindSyntax, -1, " < only support ONE expr: %s > ", text))
 	}
 	bodyType := reflect.TypeOf(f.Ast.Body[0]).String()
 	if bodyType != "*ast.ExpressionStatement" {
-		return nil, ErrFMsg("expr not support: %s", text)
+		return nil, f.withSource(newTemplateError(ErrKindSyntax, astPos(f.Ast.Body[0]), "expr not support: %s", text))
 	}
+	f.Node = lower(f.Expression())
 	return f, nil
 }
 
+// NewExprFragmentFromNode creates a fragment from IR without parsing text
+func NewExprFragmentFromNode(text string, node *Node, opMgr *OperatorsMgr, fnMgr *FnMgr) *ExprFragment {
+	return &ExprFragment{
+		Content: text,
+		Node:    node,
+		OpMgr:   opMgr,
+		FnMgr:   fnMgr,
+	}
+}
+
 func (p *ExprFragment) RawContent() string {
 	return p.Content
 }
+
+func (p *ExprFragment) withSource(err *TemplateError) *TemplateError {
+	err.Source = p.Content
+	return err
+}
+
 func ErrFMsg(format string, a ...interface{}) error {
-	logrus.Warnf(format, a...)
 	return fmt.Errorf(format, a...)
 }
 
-// 二元操作符
-func (f *ExprFragment) EvalBin(arg1, arg2 ast.Expression, op string, config *TemplateConfig) (interface{}, error) {
-	arg1Value, err := f.EvalExpr(arg1, config)
-	if err != nil {
-		return arg1Value, err
-	}
-	arg2Value, err := f.EvalExpr(arg2, config)
-	if err != nil {
-		return arg2Value, err
-	}
-	operator := f.OpMgr.GetFunc(op)
-	if operator == nil {
-		return nil, ErrFMsg("operator not found: %s", op)
-	}
-	result, err := operator(arg1Value, arg2Value)
+// callFunc calls fn with evaluated args, wrapped in the function hooks
+func (f *ExprFragment) callFunc(n *Node, fn IFn, config *TemplateConfig, args []interface{}) (interface{}, error) {
+	f.Hooks.funcCallStart(n.Name, args)
+	start := time.Now()
+	result, err := fn(config, args)
+	f.Hooks.funcCallEnd(n.Name, result, time.Since(start), err)
 	if err != nil {
-		return nil, ErrFMsg("operator error: %s", err)
+		return nil, asTemplateError(err, ErrKindFunction, n.Pos)
 	}
-
 	return result, nil
 }
 
-func (f *ExprFragment) EvalCall(funcName string, args []ast.Expression, config *TemplateConfig) (interface{}, error) {
-	fn := f.FnMgr.GetFunc(funcName)
-	if fn == nil {
-		return nil, ErrFMsg("func not found: %s", funcName)
-	}
-	var argsValue []interface{}
-	for _, arg := range args {
-		argValue, err := f.EvalExpr(arg, config)
-		if err != nil {
-			return nil, ErrFMsg("failed eval function args: %s, err: %s", funcName, err)
-		}
-		argsValue = append(argsValue, argValue)
-	}
-	result, err := fn(config, argsValue)
-	if err != nil {
-		return nil, ErrFMsg("failed eval function: %s, err: %s", funcName, err)
+// Decimalize converts numbers to decimal.Decimal, other values are returned as is
+func (f *ExprFragment) Decimalize(value interface{}) interface{} {
+	if d, ok := toDecimal(value); ok {
+		return d
 	}
-	return result, nil
+	return value
 }
 
-func (f *ExprFragment) Decimalize(value interface{}) interface{} {
+// toDecimal converts Go and json numbers to decimal.Decimal
+func toDecimal(value interface{}) (decimal.Decimal, bool) {
 	switch tp := value.(type) {
+	case decimal.Decimal:
+		return tp, true
 	case float32:
-		return decimal.NewFromFloat32(tp)
+		return decimal.NewFromFloat32(tp), true
 	case float64:
-		return decimal.NewFromFloat(tp)
+		return decimal.NewFromFloat(tp), true
+	case int:
+		return decimal.NewFromInt(int64(tp)), true
+	case int8:
+		return decimal.NewFromInt(int64(tp)), true
+	case int16:
+		return decimal.NewFromInt(int64(tp)), true
 	case int32:
-		return decimal.NewFromInt32(tp)
+		return decimal.NewFromInt32(tp), true
 	case int64:
-		return decimal.NewFromInt(tp)
+		return decimal.NewFromInt(tp), true
+	case uint:
+		return decimal.NewFromBigInt(new(big.Int).SetUint64(uint64(tp)), 0), true
+	case uint8:
+		return decimal.NewFromInt(int64(tp)), true
+	case uint16:
+		return decimal.NewFromInt(int64(tp)), true
+	case uint32:
+		return decimal.NewFromInt(int64(tp)), true
+	case uint64:
+		return decimal.NewFromBigInt(new(big.Int).SetUint64(tp), 0), true
+	case *big.Int:
+		if tp == nil {
+			return decimal.Decimal{}, false
+		}
+		return decimal.NewFromBigInt(tp, 0), true
+	case json.Number:
+		d, err := decimal.NewFromString(string(tp))
+		return d, err == nil
 	default:
-		return value
+		return decimal.Decimal{}, false
 	}
-
 }
+
+// EvalExpr evaluates a goja expression against Ctx
 func (f *ExprFragment) EvalExpr(expr ast.Expression, config *TemplateConfig) (interface{}, error) {
-	switch expr := expr.(type) {
-	case *ast.Identifier:
-		name := expr.Name.String()
-		if strings.HasPrefix(name, "$") {
-			name = strings.TrimPrefix(name, "$")
-		} else {
-			// 不支持变量
-			return name, ErrFMsg("unsupported variable: %s", name)
-		}
-		value := gjson.Get(f.Ctx, name).Value()
-		if value == nil {
-			logrus.Warnf("variable %s not found in env %s: ", name, f.Ctx)
-			return name, ErrFMsg("unknown variable: %s", name)
-		}
-		return f.Decimalize(value), nil
+	return f.compile(lower(expr))(newEvalState(context.Background(), f.Ctx, config))
+}
 
-	case *ast.BracketExpression:
-		leftValue, err := f.EvalExpr(expr.Left, config)
-		if err != nil {
-			return nil, err
-		}
-		jStr, err := json.Marshal(leftValue)
-		if err != nil {
-			return nil, ErrFMsg("failed marshal bracket left: %s err: %s", leftValue, err)
-		}
-		memberValue, err := f.EvalExpr(expr.Member, config)
-		if err != nil {
-			return nil, ErrFMsg("failed eval bracket member: %s err: %s", expr.Member, err)
-		}
-		var value interface{}
-		text := string(jStr)
-		switch m := memberValue.(type) {
-		case decimal.Decimal:
-			value = gjson.Get(text, m.BigInt().String()).Value()
-		case string:
-			// escaping path from gjson
-			m = strings.ReplaceAll(m, ".", `\.`)
-			value = gjson.Get(text, m).Value()
-		default:
-			return nil, ErrFMsg("index must be int or string, got: %s", reflect.TypeOf(m))
-		}
-		return f.Decimalize(value), nil
-	case *ast.DotExpression:
-		leftValue, err := f.EvalExpr(expr.Left, config)
-		if err != nil {
-			return nil, err
-		}
-		jStr, err := json.Marshal(leftValue)
-		if err != nil {
-			return nil, ErrFMsg("failed marshal dot left: %s err: %s", leftValue, err)
-		}
-		value := gjson.Get(string(jStr), expr.Identifier.Name.String()).Value()
-		if value == nil {
-			return nil, ErrFMsg("text %s not found in %s", expr.Identifier.Name.String(), string(jStr))
-		}
-		return f.Decimalize(value), nil
-	case *ast.BinaryExpression:
-		return f.EvalBin(expr.Left, expr.Right, expr.Operator.String(), config)
-	case *ast.CallExpression:
-		funcName, ok := expr.Callee.(*ast.Identifier)
-		if !ok {
-			return "", ErrFMsg("<function not found: %s>", funcName.Name.String())
-		}
-		return f.EvalCall(funcName.Name.String(), expr.ArgumentList, config)
-	case *ast.NumberLiteral:
-		d, err := decimal.NewFromString(fmt.Sprintf("%v", expr.Value))
-		if err != nil {
-			return nil, ErrFMsg("bad number literal %v", expr.Value)
-		}
-		return d, nil
-	case *ast.StringLiteral:
-		return expr.Value.String(), nil
-	case *ast.BooleanLiteral:
-		return expr.Value, nil
-	default:
-		return nil, ErrFMsg("expr not supported: %s", reflect.TypeOf(expr).String())
-		// 三目运算符
-		// case *ast.ConditionalExpression:
-		// 	// todo tri operator
-		// 	_ = expr.(*ast.CallExpression)
+// EvalBin evaluates the binary expression arg1 op arg2 against Ctx
+func (f *ExprFragment) EvalBin(arg1, arg2 ast.Expression, op string, config *TemplateConfig) (interface{}, error) {
+	n := &Node{Op: OpBinary, Name: op, Args: []*Node{lower(arg1), lower(arg2)}, Pos: astPos(arg1)}
+	return f.compile(n)(newEvalState(context.Background(), f.Ctx, config))
+}
+
+// EvalCall calls the function funcName with args evaluated against Ctx
+func (f *ExprFragment) EvalCall(funcName string, args []ast.Expression, config *TemplateConfig) (interface{}, error) {
+	n := &Node{Op: OpCall, Name: funcName, Pos: -1}
+	for _, arg := range args {
+		n.Args = append(n.Args, lower(arg))
 	}
+	return f.compile(n)(newEvalState(context.Background(), f.Ctx, config))
+}
+
+// program returns the compiled Node, compiled on first use
+func (f *ExprFragment) program() evalFunc {
+	f.compileOnce.Do(func() {
+		f.compiled = f.compile(f.Node)
+	})
+	return f.compiled
 }
 
+// EvalContent evaluates the expression against Ctx
 func (f *ExprFragment) EvalContent(content string, config *TemplateConfig) (interface{}, error) {
-	result, err := f.EvalExpr(f.Ast.Body[0].(*ast.ExpressionStatement).Expression, config)
+	return f.evalContent(content, newEvalState(context.Background(), f.Ctx, config))
+}
+
+func (f *ExprFragment) evalContent(content string, s *evalState) (interface{}, error) {
+	result, err := f.program()(s)
 	if err != nil {
-		return content, ErrFMsg("< eval expr err: %s, err: %s > ", content, err)
-	} else {
-		return result, nil
+		return content, f.withSource(asTemplateError(err, ErrKindType, -1))
 	}
+	return result, nil
 }
+
 func (f *ExprFragment) Eval(ctx string, config *TemplateConfig) (interface{}, error) {
 	f.Ctx = ctx
 	result, err := f.EvalContent(f.Content, config)
 	if err != nil {
 		return result, err
 	}
+	formatted, _ := formatResult(result)
+	return formatted, nil
+}
 
+// formatResult applies the display formatting of expression results and
+// reports which formatting was used
+func formatResult(result interface{}) (interface{}, Formatting) {
 	switch r := result.(type) {
 	case string:
 		decRepr, err := decimal.NewFromString(r)
 		if err != nil {
-			return result, nil
+			return result, FormattingNone
 		}
-		return thousandSepAndRound(decRepr), nil
+		return thousandSepAndRound(decRepr), FormattingNumber
 	case decimal.Decimal:
-		return thousandSepAndRound(r), nil
+		return thousandSepAndRound(r), FormattingNumber
+	case time.Duration:
+		return r.String(), FormattingNone
 	default:
-		return result, nil
+		return result, FormattingJSON
 	}
-
 }
 
 func formatIntThousandSep(num string) string {
@@ -2,6 +2,8 @@ package go_template
 
 import (
 	"encoding/json"
+	"github.com/dop251/goja/ast"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 	"math/big"
 	"strconv"
@@ -333,3 +335,73 @@ func TestThousandSepFloate18(t *testing.T) {
 	s, _ := json.Marshal(v)
 	assert.Equal(t, `"10"`, string(s))
 }
+
+func TestExactContextNumber(t *testing.T) {
+	got, err := NewExprFragment(`$value + 1`, NewOperatorsMgr(), NewFnMgr())
+	if err != nil {
+		t.Error(err)
+	}
+	got.Ctx = `{"value": 8912239900000000000123}`
+	res, err := got.EvalContent(got.Content, config)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, "8912239900000000000124", res.(decimal.Decimal).String())
+
+	got, _ = NewExprFragment(`$o`, NewOperatorsMgr(), NewFnMgr())
+	res, err = got.Eval(`{"o": {"wei": 8912239900000000000123}}`, config)
+	if err != nil {
+		t.Error(err)
+	}
+	data, _ := json.Marshal(res)
+	assert.Equal(t, `{"wei":8912239900000000000123}`, string(data))
+}
+
+func TestDecimalize(t *testing.T) {
+	f := &ExprFragment{}
+	wei, _ := new(big.Int).SetString("8912239900000000000123", 10)
+	for value, expect := range map[interface{}]string{
+		int(-3):                      "-3",
+		uint8(7):                     "7",
+		uint64(18446744073709551615): "18446744073709551615",
+		json.Number("1.25"):          "1.25",
+		wei:                          "8912239900000000000123",
+	} {
+		d, ok := f.Decimalize(value).(decimal.Decimal)
+		if !ok || d.String() != expect {
+			t.Errorf("%T %v: expect %s, got %v", value, value, expect, f.Decimalize(value))
+		}
+	}
+	assert.Equal(t, "x", f.Decimalize("x"))
+}
+
+func TestBuiltinArgCount(t *testing.T) {
+	// fragments evaluated directly skip the signature check of templates
+	for _, src := range []string{`time()`, `duration()`, `formatTime()`, `round(1)`} {
+		f, err := NewExprFragment(src, NewOperatorsMgr(), NewFnMgr())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Eval("{}", config); err == nil {
+			t.Errorf("%s: expect error", src)
+		}
+	}
+}
+
+func TestEvalBinAndCall(t *testing.T) {
+	f, err := NewExprFragment(`round($a / 3, 2)`, NewOperatorsMgr(), NewFnMgr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Ctx = `{"a": 10}`
+	call := f.Expression().(*ast.CallExpression)
+	res, err := f.EvalCall("round", call.ArgumentList, config)
+	if err != nil || res.(decimal.Decimal).String() != "3.33" {
+		t.Errorf("expect 3.33, got %v %v", res, err)
+	}
+	bin := call.ArgumentList[0].(*ast.BinaryExpression)
+	res, err = f.EvalBin(bin.Left, bin.Right, "*", config)
+	if err != nil || res.(decimal.Decimal).String() != "30" {
+		t.Errorf("expect 30, got %v %v", res, err)
+	}
+}
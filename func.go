@@ -2,6 +2,8 @@ package go_template
 
 import (
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/shopspring/decimal"
@@ -9,8 +11,54 @@ import (
 
 type IFn func(config *TemplateConfig, args []interface{}) (interface{}, error)
 
+// ArgKind is the expected kind of a function argument
+type ArgKind string
+
+const (
+	ArgAny    ArgKind = "any"
+	ArgNumber ArgKind = "number"
+	ArgString ArgKind = "string"
+	ArgBool   ArgKind = "bool"
+	ArgTime   ArgKind = "time" // time string or timestamp
+)
+
+// Variadic as Signature.MaxArgs accepts any number of args
+const Variadic = -1
+
+// Signature describes the args a function accepts, checked when templates are parsed
+type Signature struct {
+	MinArgs int
+	MaxArgs int
+	// kind of each arg, the last one applies to the rest of variadic args
+	Args []ArgKind
+}
+
+// ArgKind returns expected kind of the i-th arg
+func (s *Signature) ArgKind(i int) ArgKind {
+	if len(s.Args) == 0 {
+		return ArgAny
+	}
+	if i >= len(s.Args) {
+		return s.Args[len(s.Args)-1]
+	}
+	return s.Args[i]
+}
+
+// FnMgr holds functions, register and look them up through its methods,
+// they are safe for concurrent use
 type FnMgr struct {
-	Funcs map[string]IFn
+	// Funcs holds only the functions registered on this manager, not the
+	// inherited ones, use Names and GetFunc to see all. Register through the
+	// methods, writing it directly isn't safe while templates render
+	Funcs      map[string]IFn
+	Signatures map[string]*Signature
+	// capabilities templates must be granted to call the function, see SetCapabilities
+	Capabilities map[string][]string
+
+	mu          sync.RWMutex
+	parent      *FnMgr
+	consts      *ConstMgr // constants of the engine, functions can't be named after them
+	capsVersion uint64    // bumped by SetCapabilities
 }
 
 func tryParseTime(timeStr string, config *TemplateConfig) (time.Time, error) {
@@ -25,44 +73,31 @@ func tryParseTime(timeStr string, config *TemplateConfig) (time.Time, error) {
 	return time.Time{}, fmt.Errorf("unknown time format: %s", timeStr)
 }
 
-func withTimezone(config *TemplateConfig, args []interface{}) (interface{}, error) {
-	var timeOffset = config.TimeOffset
-	var timeFormat = config.TimeFormat
-	if len(args) >= 2 {
-		offset, ok := args[1].(decimal.Decimal)
-		if !ok {
-			return nil, ErrFMsg("format arg1 must be timezone: %v", args[1])
-		}
-		timeOffset = int(offset.IntPart())
+// toTime converts a time string, timestamp or time.Time to time.Time
+func toTime(arg interface{}, config *TemplateConfig) (time.Time, error) {
+	if t, ok := arg.(time.Time); ok {
+		return t, nil
 	}
-	if len(args) == 3 {
-		format, ok := args[2].(string)
-		if !ok {
-			return nil, ErrFMsg("format arg2 must be format string: %v", args[2])
-		}
-		timeFormat = format
-	}
-
 	var dt time.Time
 	var err error
-	dtStr, ok := args[0].(string)
+	dtStr, ok := arg.(string)
 	if !ok {
 		// maybe timestamp int64
-		if ts, ok := args[0].(decimal.Decimal); ok {
+		if ts, ok := arg.(decimal.Decimal); ok {
 			if ts.GreaterThan(decimal.NewFromInt(10000000000)) {
 				dt = time.UnixMilli(ts.IntPart())
 			} else {
 				dt = time.Unix(ts.IntPart(), 0)
 			}
 		} else {
-			return nil, ErrFMsg("timezone arg0 must be string|int64|uint64: %v", dtStr)
+			return dt, newTemplateError(ErrKindType, -1, "timezone arg0 must be string|int64|uint64: %v", dtStr)
 		}
 	} else {
 		dt, err = tryParseTime(dtStr, config)
 		if err != nil {
 			ts, err := decimal.NewFromString(dtStr)
 			if err != nil {
-				return nil, ErrFMsg("timezone arg0 must be rfc3339nano format or timestamp: %s", dtStr)
+				return dt, newTemplateError(ErrKindType, -1, "timezone arg0 must be rfc3339nano format or timestamp: %s", dtStr)
 			}
 			if ts.GreaterThan(decimal.NewFromInt(10000000000)) {
 				dt = time.UnixMilli(ts.IntPart())
@@ -71,37 +106,197 @@ func withTimezone(config *TemplateConfig, args []interface{}) (interface{}, erro
 			}
 		}
 	}
+	return dt, nil
+}
+
+func withTimezone(config *TemplateConfig, args []interface{}) (interface{}, error) {
+	if len(args) < 1 || len(args) > 3 {
+		return nil, newTemplateError(ErrKindType, -1, "format accept 1 to 3 args, got: %d", len(args))
+	}
+	var timeOffset = config.TimeOffset
+	var timeFormat = config.TimeFormat
+	if len(args) >= 2 {
+		offset, ok := args[1].(decimal.Decimal)
+		if !ok {
+			return nil, newTemplateError(ErrKindType, -1, "format arg1 must be timezone: %v", args[1])
+		}
+		timeOffset = int(offset.IntPart())
+	}
+	if len(args) == 3 {
+		format, ok := args[2].(string)
+		if !ok {
+			return nil, newTemplateError(ErrKindType, -1, "format arg2 must be format string: %v", args[2])
+		}
+		timeFormat = format
+	}
+
+	dt, err := toTime(args[0], config)
+	if err != nil {
+		return nil, err
+	}
 
 	return FormatTime(dt, timeOffset, timeFormat), nil
 }
 
 func NewFnMgr() *FnMgr {
+	timeSignature := &Signature{MinArgs: 1, MaxArgs: 3, Args: []ArgKind{ArgTime, ArgNumber, ArgString}}
 	return &FnMgr{
 		Funcs: map[string]IFn{
-			"round": func(config *TemplateConfig, args []interface{}) (interface{}, error) {
+			"round": ValueFn(func(config *TemplateConfig, args []Value) (Value, error) {
 				if len(args) != 2 {
-					return nil, ErrFMsg("round only accept 2 arg, got: %d", len(args))
+					return NullValue(), newTemplateError(ErrKindType, -1, "round only accept 2 arg, got: %d", len(args))
 				}
-				n, ok := args[0].(decimal.Decimal)
-				if !ok {
-					return nil, ErrFMsg("round with NaN: %v", n)
+				n, err := args[0].AsNumber()
+				if err != nil {
+					return NullValue(), err
 				}
-				place, ok := args[1].(decimal.Decimal)
-				if !ok {
-					return nil, ErrFMsg("round to NaN: %v", place)
+				place, err := args[1].AsNumber()
+				if err != nil {
+					return NullValue(), err
 				}
-				return n.Round(int32(place.BigInt().Int64())), nil
-			},
+				return NumberValue(n.Round(int32(place.IntPart()))), nil
+			}).IFn(),
 			"timezone":   withTimezone,
 			"formatTime": withTimezone,
+			"time": func(config *TemplateConfig, args []interface{}) (interface{}, error) {
+				if len(args) != 1 {
+					return nil, newTemplateError(ErrKindType, -1, "time only accept 1 arg, got: %d", len(args))
+				}
+				return toTime(args[0], config)
+			},
+			"duration": ValueFn(func(config *TemplateConfig, args []Value) (Value, error) {
+				if len(args) != 1 {
+					return NullValue(), newTemplateError(ErrKindType, -1, "duration only accept 1 arg, got: %d", len(args))
+				}
+				d, err := args[0].AsDuration()
+				return DurationValue(d), err
+			}).IFn(),
+		},
+		Signatures: map[string]*Signature{
+			"round":      {MinArgs: 2, MaxArgs: 2, Args: []ArgKind{ArgNumber, ArgNumber}},
+			"timezone":   timeSignature,
+			"formatTime": timeSignature,
+			"time":       {MinArgs: 1, MaxArgs: 1, Args: []ArgKind{ArgTime}},
+			"duration":   {MinArgs: 1, MaxArgs: 1, Args: []ArgKind{ArgString}},
 		},
+		Capabilities: map[string][]string{},
 	}
 }
 
-func (f *FnMgr) RegisterFunc(name string, fn IFn) {
+// Fork creates a manager whose lookups fall back to f, functions
+// registered on it shadow the ones of f without affecting it
+func (f *FnMgr) Fork() *FnMgr {
+	return &FnMgr{
+		Funcs:        map[string]IFn{},
+		Signatures:   map[string]*Signature{},
+		Capabilities: map[string][]string{},
+		parent:       f,
+	}
+}
+
+// RegisterFunc registers fn without signature, only its name is checked when
+// parsing templates. Names of constants of the engine are rejected
+func (f *FnMgr) RegisterFunc(name string, fn IFn) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.checkConstant(name); err != nil {
+		return err
+	}
 	f.Funcs[name] = fn
+	delete(f.Signatures, name)
+	return nil
+}
+
+// RegisterFuncWithSignature registers fn, calls are checked against sig when
+// parsing templates. Names of constants of the engine are rejected
+func (f *FnMgr) RegisterFuncWithSignature(name string, fn IFn, sig Signature) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.checkConstant(name); err != nil {
+		return err
+	}
+	f.Funcs[name] = fn
+	if f.Signatures == nil {
+		f.Signatures = map[string]*Signature{}
+	}
+	f.Signatures[name] = &sig
+	return nil
+}
+
+// checkConstant rejects names of constants, inherited ones included
+func (f *FnMgr) checkConstant(name string) error {
+	if _, ok := f.consts.Get(name); ok {
+		return fmt.Errorf("function %s collides with constant %s", name, name)
+	}
+	return nil
+}
+
+// SetCapabilities tags a function, templates calling it must be granted all
+// of caps through TemplateConfig.Capabilities. Functions without capabilities
+// can be called by every template. Tags are kept when the function is
+// registered again or shadowed by a child manager, SetCapabilities(name)
+// without caps clears them. Templates are checked again on their next render
+func (f *FnMgr) SetCapabilities(name string, caps ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Capabilities == nil {
+		f.Capabilities = map[string][]string{}
+	}
+	f.Capabilities[name] = append([]string{}, caps...)
+	atomic.AddUint64(&f.capsVersion, 1)
+}
+
+// GetCapabilities returns the capabilities required to call the function
+func (f *FnMgr) GetCapabilities(name string) []string {
+	f.mu.RLock()
+	caps, tagged := f.Capabilities[name]
+	f.mu.RUnlock()
+	if !tagged && f.parent != nil {
+		return f.parent.GetCapabilities(name)
+	}
+	return caps
+}
+
+// capabilitiesVersion changes whenever capabilities of f or its parents change
+func (f *FnMgr) capabilitiesVersion() uint64 {
+	var version uint64
+	for m := f; m != nil; m = m.parent {
+		version += atomic.LoadUint64(&m.capsVersion)
+	}
+	return version
+}
+
+// GetSignature returns nil for functions registered without signature
+func (f *FnMgr) GetSignature(name string) *Signature {
+	f.mu.RLock()
+	_, own := f.Funcs[name]
+	sig := f.Signatures[name]
+	f.mu.RUnlock()
+	if !own && f.parent != nil {
+		return f.parent.GetSignature(name)
+	}
+	return sig
 }
 
 func (f *FnMgr) GetFunc(name string) IFn {
-	return f.Funcs[name]
+	f.mu.RLock()
+	fn, ok := f.Funcs[name]
+	f.mu.RUnlock()
+	if !ok && f.parent != nil {
+		return f.parent.GetFunc(name)
+	}
+	return fn
+}
+
+// Names returns the sorted names of all functions, inherited ones included
+func (f *FnMgr) Names() []string {
+	seen := map[string]bool{}
+	for m := f; m != nil; m = m.parent {
+		m.mu.RLock()
+		for name := range m.Funcs {
+			seen[name] = true
+		}
+		m.mu.RUnlock()
+	}
+	return sortedKeys(seen)
 }
@@ -5,6 +5,7 @@ go 1.19
 require (
 	github.com/dop251/goja v0.0.0-20220915101355-d79e1b125a30
 	github.com/stretchr/testify v1.7.2
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -12,14 +13,11 @@ require (
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/tidwall/match v1.1.1 // indirect
 	github.com/tidwall/pretty v1.2.0 // indirect
-	golang.org/x/sys v0.0.0-20220715151400-c0bba94af5f8 // indirect
 	golang.org/x/text v0.3.7 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
 
 require (
 	github.com/go-sourcemap/sourcemap v2.1.3+incompatible // indirect
 	github.com/shopspring/decimal v1.3.1
-	github.com/sirupsen/logrus v1.9.0
 	github.com/tidwall/gjson v1.14.3
 )
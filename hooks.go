@@ -0,0 +1,119 @@
+package go_template
+
+import (
+	"time"
+)
+
+// RenderHook is notified around every render
+type RenderHook interface {
+	BeforeRender(t *Template)
+	AfterRender(t *Template, report *RenderReport, elapsed time.Duration, err error)
+}
+
+// FragmentHook is notified around every fragment evaluation
+type FragmentHook interface {
+	BeforeFragment(t *Template, index int)
+	AfterFragment(t *Template, result *FragmentResult, elapsed time.Duration)
+}
+
+// FuncHook is notified around every function call
+type FuncHook interface {
+	FuncCallStart(name string, args []interface{})
+	FuncCallEnd(name string, result interface{}, elapsed time.Duration, err error)
+}
+
+// Hooks holds registered hooks, register them before rendering
+type Hooks struct {
+	render   []RenderHook
+	fragment []FragmentHook
+	fn       []FuncHook
+}
+
+func NewHooks() *Hooks {
+	return &Hooks{}
+}
+
+// Add registers hook for every hook interface it implements,
+// returns false if it implements none
+func (h *Hooks) Add(hook interface{}) bool {
+	added := false
+	if r, ok := hook.(RenderHook); ok {
+		h.render = append(h.render, r)
+		added = true
+	}
+	if f, ok := hook.(FragmentHook); ok {
+		h.fragment = append(h.fragment, f)
+		added = true
+	}
+	if f, ok := hook.(FuncHook); ok {
+		h.fn = append(h.fn, f)
+		added = true
+	}
+	return added
+}
+
+// fork copies the registered hooks, hooks added to the copy don't affect h
+func (h *Hooks) fork() *Hooks {
+	if h == nil {
+		return nil
+	}
+	return &Hooks{
+		render:   append([]RenderHook(nil), h.render...),
+		fragment: append([]FragmentHook(nil), h.fragment...),
+		fn:       append([]FuncHook(nil), h.fn...),
+	}
+}
+
+func (h *Hooks) beforeRender(t *Template) {
+	if h == nil {
+		return
+	}
+	for _, hook := range h.render {
+		hook.BeforeRender(t)
+	}
+}
+
+func (h *Hooks) afterRender(t *Template, report *RenderReport, elapsed time.Duration, err error) {
+	if h == nil {
+		return
+	}
+	for _, hook := range h.render {
+		hook.AfterRender(t, report, elapsed, err)
+	}
+}
+
+func (h *Hooks) beforeFragment(t *Template, index int) {
+	if h == nil {
+		return
+	}
+	for _, hook := range h.fragment {
+		hook.BeforeFragment(t, index)
+	}
+}
+
+func (h *Hooks) afterFragment(t *Template, result *FragmentResult, elapsed time.Duration) {
+	if h == nil {
+		return
+	}
+	for _, hook := range h.fragment {
+		hook.AfterFragment(t, result, elapsed)
+	}
+}
+
+func (h *Hooks) funcCallStart(name string, args []interface{}) {
+	if h == nil {
+		return
+	}
+	for _, hook := range h.fn {
+		hook.FuncCallStart(name, args)
+	}
+}
+
+func (h *Hooks) funcCallEnd(name string, result interface{}, elapsed time.Duration, err error) {
+	if h == nil {
+		return
+	}
+	for _, hook := range h.fn {
+		hook.FuncCallEnd(name, result, elapsed, err)
+	}
+}
@@ -0,0 +1,69 @@
+package go_template
+
+import (
+	"testing"
+	"time"
+)
+
+type recordHook struct {
+	events []string
+}
+
+func (h *recordHook) BeforeRender(_ *Template) {
+	h.events = append(h.events, "before render")
+}
+
+func (h *recordHook) AfterRender(_ *Template, report *RenderReport, _ time.Duration, _ error) {
+	h.events = append(h.events, "after render "+report.Output)
+}
+
+func (h *recordHook) BeforeFragment(_ *Template, _ int) {
+	h.events = append(h.events, "before fragment")
+}
+
+func (h *recordHook) AfterFragment(_ *Template, r *FragmentResult, _ time.Duration) {
+	h.events = append(h.events, "after fragment "+r.Text)
+}
+
+func (h *recordHook) FuncCallStart(name string, _ []interface{}) {
+	h.events = append(h.events, "call "+name)
+}
+
+func (h *recordHook) FuncCallEnd(name string, _ interface{}, _ time.Duration, err error) {
+	if err != nil {
+		h.events = append(h.events, "fail "+name)
+	} else {
+		h.events = append(h.events, "end "+name)
+	}
+}
+
+func TestHooks(t *testing.T) {
+	engine := NewTemplateEngine()
+	hook := &recordHook{}
+	if !engine.AddHook(hook) {
+		t.Fatal("hook not registered")
+	}
+	if engine.AddHook(struct{}{}) {
+		t.Error("expect non hook rejected")
+	}
+	tp, err := NewTemplate("x{round(1.234, 1)}{round($a, 1)}", engine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = tp.Render(`{"a": "x"}`)
+	expect := []string{
+		"before render",
+		"before fragment", "after fragment x",
+		"before fragment", "call round", "end round", "after fragment 1.2",
+		"before fragment", "call round", "fail round", "after fragment {round($a, 1)}",
+		"after render x1.2{round($a, 1)}",
+	}
+	if len(hook.events) != len(expect) {
+		t.Fatalf("expect %v, got %v", expect, hook.events)
+	}
+	for i := range expect {
+		if hook.events[i] != expect[i] {
+			t.Errorf("event %d: expect %s, got %s", i, expect[i], hook.events[i])
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package go_template
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/dop251/goja/ast"
+)
+
+// Position of a node in the template
+type Position struct {
+	Fragment int // index of the fragment
+	Line     int // 1-based
+	Column   int // 1-based
+}
+
+// Reference is a variable or function referenced by a template,
+// with every place it appears
+type Reference struct {
+	Name  string
+	Sites []Position
+}
+
+// Variables returns normalized paths of $variables referenced by the template
+// like `a.b[0].c`, in order of first appearance. Dynamic indexes are written as [*]
+func (t *Template) Variables() []Reference {
+	return t.references(func(f *ExprFragment, add func(string, int)) {
+		f.walkVariables(add)
+	})
+}
+
+// Constants returns bare identifiers referenced by the template, in order of
+// first appearance. They evaluate to constants of the engine, others fail
+func (t *Template) Constants() []Reference {
+	return t.references(func(f *ExprFragment, add func(string, int)) {
+		f.walkConstants(add)
+	})
+}
+
+// Functions returns names of functions called by the template, in order of first appearance
+func (t *Template) Functions() []Reference {
+	return t.references(func(f *ExprFragment, add func(string, int)) {
+		f.walkFunctions(add)
+	})
+}
+
+func (t *Template) references(walk func(f *ExprFragment, add func(string, int))) []Reference {
+	var refs []Reference
+	index := map[string]int{}
+	for i, fragment := range t.parsedTemplate {
+		f, ok := fragment.(*ExprFragment)
+		if !ok {
+			continue
+		}
+		walk(f, func(name string, offset int) {
+			pos := t.position(i, offset)
+			if j, ok := index[name]; ok {
+				refs[j].Sites = append(refs[j].Sites, pos)
+				return
+			}
+			index[name] = len(refs)
+			refs = append(refs, Reference{Name: name, Sites: []Position{pos}})
+		})
+	}
+	return refs
+}
+
+// position of the byte offset in the expression of fragment i
+func (t *Template) position(i int, offset int) Position {
+	// skip the opening {
+	line, column := lineColumn(t.templateText, t.spans[i].Start+1+offset)
+	return Position{Fragment: i, Line: line, Column: column}
+}
+
+// Expression returns the parsed expression, nil for fragments of precompiled templates
+func (f *ExprFragment) Expression() ast.Expression {
+	if f.Ast == nil {
+		return nil
+	}
+	return f.Ast.Body[0].(*ast.ExpressionStatement).Expression
+}
+
+func (f *ExprFragment) walkVariables(add func(string, int)) {
+	walkNode(f.Node, func(n *Node) bool {
+		root, path, ok := variablePath(n)
+		if !ok {
+			return true
+		}
+		add(path, root.Pos)
+		// variables used as dynamic indexes
+		for _, member := range dynamicMembers(n) {
+			walkNode(member, func(m *Node) bool {
+				if root, path, ok := variablePath(m); ok {
+					add(path, root.Pos)
+					return false
+				}
+				return true
+			})
+		}
+		return false
+	})
+}
+
+func (f *ExprFragment) walkConstants(add func(string, int)) {
+	walkNode(f.Node, func(n *Node) bool {
+		if n.Op == OpIdentifier {
+			add(n.Name, n.Pos)
+		}
+		return true
+	})
+}
+
+func (f *ExprFragment) walkFunctions(add func(string, int)) {
+	walkNode(f.Node, func(n *Node) bool {
+		if n.Op == OpCall && n.Name != "" {
+			add(n.Name, n.Pos)
+		}
+		return true
+	})
+}
+
+type segmentKind int
+
+const (
+	segmentKey     segmentKind = iota // .key or ['key']
+	segmentIndex                      // [0]
+	segmentDynamic                    // [$i]
+)
+
+// pathSegment is one step of a $variable access chain
+type pathSegment struct {
+	Kind  segmentKind
+	Key   string
+	Index string
+}
+
+func (s pathSegment) String() string {
+	switch s.Kind {
+	case segmentIndex:
+		return "[" + s.Index + "]"
+	case segmentDynamic:
+		return "[*]"
+	default:
+		if isPlainKey(s.Key) {
+			return "." + s.Key
+		}
+		return "[" + strconv.Quote(s.Key) + "]"
+	}
+}
+
+// variablePath returns the root variable and normalized path of a
+// $variable access chain
+func variablePath(n *Node) (*Node, string, bool) {
+	root, segments, ok := pathSegments(n)
+	if !ok {
+		return nil, "", false
+	}
+	path := strings.Builder{}
+	for i, s := range segments {
+		text := s.String()
+		if i == 0 {
+			text = strings.TrimPrefix(text, ".")
+		}
+		path.WriteString(text)
+	}
+	return root, path.String(), true
+}
+
+// pathSegments splits a $variable access chain, the root variable is the first segment
+func pathSegments(n *Node) (*Node, []pathSegment, bool) {
+	switch n.Op {
+	case OpVariable:
+		return n, []pathSegment{{Kind: segmentKey, Key: n.Name}}, true
+	case OpDot:
+		root, segments, ok := pathSegments(n.Args[0])
+		if !ok {
+			return nil, nil, false
+		}
+		return root, append(segments, pathSegment{Kind: segmentKey, Key: n.Name}), true
+	case OpIndex:
+		root, segments, ok := pathSegments(n.Args[0])
+		if !ok {
+			return nil, nil, false
+		}
+		switch m := n.Args[1]; m.Op {
+		case OpNumber:
+			return root, append(segments, pathSegment{Kind: segmentIndex, Index: m.Value}), true
+		case OpString:
+			return root, append(segments, pathSegment{Kind: segmentKey, Key: m.Value}), true
+		default:
+			return root, append(segments, pathSegment{Kind: segmentDynamic}), true
+		}
+	default:
+		return nil, nil, false
+	}
+}
+
+// dynamicMembers returns non literal indexes of an access chain
+func dynamicMembers(n *Node) []*Node {
+	var members []*Node
+	for {
+		switch n.Op {
+		case OpDot:
+			n = n.Args[0]
+		case OpIndex:
+			if m := n.Args[1]; m.Op != OpNumber && m.Op != OpString {
+				members = append(members, m)
+			}
+			n = n.Args[0]
+		default:
+			return members
+		}
+	}
+}
+
+// isPlainKey reports whether key can be written as .key in a path
+func isPlainKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for i, ch := range key {
+		if ch == '_' || ch == '$' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') {
+			continue
+		}
+		if i > 0 && ch >= '0' && ch <= '9' {
+			continue
+		}
+		return false
+	}
+	return true
+}
@@ -0,0 +1,40 @@
+package go_template
+
+import (
+	"testing"
+)
+
+func TestTemplate_Variables(t *testing.T) {
+	tp, err := NewTemplate("{$a.b[0].c + $x} and\n{$a['b.c'] * $a.b[0].c} {$list[$i].name}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vars := tp.Variables()
+	expect := []string{"a.b[0].c", "x", `a["b.c"]`, "list[*].name", "i"}
+	if len(vars) != len(expect) {
+		t.Fatalf("expect %v, got %+v", expect, vars)
+	}
+	for i, v := range vars {
+		if v.Name != expect[i] {
+			t.Errorf("expect %s, got %s", expect[i], v.Name)
+		}
+	}
+	sites := vars[0].Sites
+	if len(sites) != 2 || sites[0] != (Position{0, 1, 2}) || sites[1] != (Position{2, 2, 14}) {
+		t.Errorf("unexpected sites: %+v", sites)
+	}
+}
+
+func TestTemplate_Functions(t *testing.T) {
+	tp, err := NewTemplate("{round($a, 2)} {timezone($t, round($o, 0))}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fns := tp.Functions()
+	if len(fns) != 2 || fns[0].Name != "round" || fns[1].Name != "timezone" {
+		t.Fatalf("unexpected functions: %+v", fns)
+	}
+	if len(fns[0].Sites) != 2 || fns[0].Sites[1].Column != 30 {
+		t.Errorf("unexpected sites: %+v", fns[0].Sites)
+	}
+}
@@ -0,0 +1,130 @@
+package go_template
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/dop251/goja/ast"
+)
+
+// NodeOp is the operation of an IR node
+type NodeOp string
+
+const (
+	OpVariable    NodeOp = "var"   // $Name
+	OpIdentifier  NodeOp = "ident" // bare Name
+	OpDot         NodeOp = "dot"   // Args[0].Name
+	OpIndex       NodeOp = "index" // Args[0][Args[1]]
+	OpBinary      NodeOp = "bin"   // Args[0] Name Args[1]
+	OpCall        NodeOp = "call"  // Name(Args...)
+	OpNumber      NodeOp = "num"   // Value
+	OpString      NodeOp = "str"   // Value
+	OpBool        NodeOp = "bool"  // Value
+	OpUnsupported NodeOp = "bad"   // Name is the unsupported syntax, fails on eval
+)
+
+// Node is the compact IR of an expression, expressions are evaluated from
+// it and precompiled templates store it instead of the goja AST
+type Node struct {
+	Op    NodeOp  `json:"o"`
+	Name  string  `json:"n,omitempty"`
+	Value string  `json:"v,omitempty"`
+	Args  []*Node `json:"a,omitempty"`
+	Pos   int     `json:"p"` // byte offset in the expression source
+}
+
+// astPos converts goja 1-based file index to a byte offset in the expression
+func astPos(node ast.Node) int {
+	return int(node.Idx0()) - 1
+}
+
+// lower converts goja AST to IR
+func lower(expr ast.Expression) *Node {
+	switch expr := expr.(type) {
+	case *ast.Identifier:
+		name := expr.Name.String()
+		if strings.HasPrefix(name, "$") {
+			return &Node{Op: OpVariable, Name: strings.TrimPrefix(name, "$"), Pos: astPos(expr)}
+		}
+		return &Node{Op: OpIdentifier, Name: name, Pos: astPos(expr)}
+	case *ast.DotExpression:
+		return &Node{Op: OpDot, Name: expr.Identifier.Name.String(), Args: []*Node{lower(expr.Left)}, Pos: astPos(&expr.Identifier)}
+	case *ast.BracketExpression:
+		return &Node{Op: OpIndex, Args: []*Node{lower(expr.Left), lower(expr.Member)}, Pos: astPos(expr)}
+	case *ast.BinaryExpression:
+		return &Node{Op: OpBinary, Name: expr.Operator.String(), Args: []*Node{lower(expr.Left), lower(expr.Right)}, Pos: astPos(expr)}
+	case *ast.CallExpression:
+		n := &Node{Op: OpCall, Pos: astPos(expr)}
+		// only named functions can be called, Name is left empty otherwise
+		if callee, ok := expr.Callee.(*ast.Identifier); ok {
+			n.Name = callee.Name.String()
+		}
+		for _, arg := range expr.ArgumentList {
+			n.Args = append(n.Args, lower(arg))
+		}
+		return n
+	case *ast.NumberLiteral:
+		return &Node{Op: OpNumber, Value: fmt.Sprintf("%v", expr.Value), Pos: astPos(expr)}
+	case *ast.StringLiteral:
+		return &Node{Op: OpString, Value: expr.Value.String(), Pos: astPos(expr)}
+	case *ast.BooleanLiteral:
+		return &Node{Op: OpBool, Value: strconv.FormatBool(expr.Value), Pos: astPos(expr)}
+	case *ast.ConditionalExpression:
+		// children are kept for introspection
+		return &Node{Op: OpUnsupported, Name: reflect.TypeOf(expr).String(), Pos: astPos(expr),
+			Args: []*Node{lower(expr.Test), lower(expr.Consequent), lower(expr.Alternate)}}
+	case *ast.UnaryExpression:
+		return &Node{Op: OpUnsupported, Name: reflect.TypeOf(expr).String(), Pos: astPos(expr),
+			Args: []*Node{lower(expr.Operand)}}
+	default:
+		return &Node{Op: OpUnsupported, Name: reflect.TypeOf(expr).String(), Pos: astPos(expr)}
+	}
+}
+
+// nodeArgs is the number of args of each op, -1 for any
+var nodeArgs = map[NodeOp]int{
+	OpVariable:    0,
+	OpIdentifier:  0,
+	OpDot:         1,
+	OpIndex:       2,
+	OpBinary:      2,
+	OpCall:        -1,
+	OpNumber:      0,
+	OpString:      0,
+	OpBool:        0,
+	OpUnsupported: -1,
+}
+
+// check reports IR which lower doesn't produce, like IR decoded from corrupted data
+func (n *Node) check() error {
+	if n == nil {
+		return errors.New("nil node")
+	}
+	count, ok := nodeArgs[n.Op]
+	if !ok {
+		return fmt.Errorf("unknown op %q", n.Op)
+	}
+	if count >= 0 && len(n.Args) != count {
+		return fmt.Errorf("%s expects %d args, got %d", n.Op, count, len(n.Args))
+	}
+	for _, arg := range n.Args {
+		if err := arg.check(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// walkNode visits n and its children depth first, children are skipped
+// when visit returns false
+func walkNode(n *Node, visit func(*Node) bool) {
+	if n == nil || !visit(n) {
+		return
+	}
+	for _, arg := range n.Args {
+		walkNode(arg, visit)
+	}
+}
@@ -0,0 +1,52 @@
+package go_template
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Kind classifies values flowing through expressions
+type Kind string
+
+const (
+	KindAny      Kind = "any" // matches every kind when registering typed operators
+	KindNull     Kind = "null"
+	KindNumber   Kind = "number"
+	KindString   Kind = "string"
+	KindBool     Kind = "bool"
+	KindTime     Kind = "time"
+	KindDuration Kind = "duration"
+	KindList     Kind = "list"
+	KindMap      Kind = "map"
+	KindCustom   Kind = "custom" // other Go values returned by functions
+)
+
+// KindOf classifies value. Numeric strings are numbers, the context keeps
+// numbers which don't fit float64 in strings
+func KindOf(value interface{}) Kind {
+	if _, ok := toDecimal(value); ok {
+		return KindNumber
+	}
+	switch v := value.(type) {
+	case nil:
+		return KindNull
+	case string:
+		if _, err := decimal.NewFromString(v); err == nil {
+			return KindNumber
+		}
+		return KindString
+	case bool:
+		return KindBool
+	case time.Time:
+		return KindTime
+	case time.Duration:
+		return KindDuration
+	case []interface{}:
+		return KindList
+	case map[string]interface{}:
+		return KindMap
+	default:
+		return KindCustom
+	}
+}
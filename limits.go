@@ -0,0 +1,67 @@
+package go_template
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Limits bound the work of a render, for templates written by untrusted
+// authors. Zero values are unlimited
+type Limits struct {
+	MaxSteps       int // expression nodes evaluated in a render
+	MaxOutputBytes int // size of the rendered output
+	MaxDepth       int // nesting of expression evaluation
+}
+
+// Errors of exceeded limits, a render aborted by its context.Context
+// returns the error of the context
+var (
+	ErrMaxSteps  = errors.New("max evaluation steps exceeded")
+	ErrMaxOutput = errors.New("max output bytes exceeded")
+	ErrMaxDepth  = errors.New("max evaluation depth exceeded")
+)
+
+// the context is checked every checkInterval steps
+const checkInterval = 64
+
+// enter counts the evaluation of n against the limits
+func (s *evalState) enter(n *Node) error {
+	s.steps++
+	s.depth++
+	limits := s.config.Limits
+	if limits.MaxSteps > 0 && s.steps > limits.MaxSteps {
+		return limitError(n.Pos, ErrMaxSteps, limits.MaxSteps)
+	}
+	if limits.MaxDepth > 0 && s.depth > limits.MaxDepth {
+		return limitError(n.Pos, ErrMaxDepth, limits.MaxDepth)
+	}
+	if s.steps%checkInterval == 0 {
+		if err := s.rctx.Err(); err != nil {
+			te := newTemplateError(ErrKindLimit, n.Pos, "render aborted: %s", err)
+			te.Err = err
+			return te
+		}
+	}
+	return nil
+}
+
+func limitError(pos int, err error, limit int) *TemplateError {
+	te := newTemplateError(ErrKindLimit, pos, "%s: %d", err, limit)
+	te.Err = err
+	return te
+}
+
+// isAbort reports whether err stops the whole render
+func isAbort(err error) bool {
+	return errors.Is(err, ErrMaxSteps) || errors.Is(err, ErrMaxOutput) || errors.Is(err, ErrMaxDepth) ||
+		errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// checkOutput checks the output size after appending n bytes
+func (s *evalState) checkOutput(size int) error {
+	if max := s.config.Limits.MaxOutputBytes; max > 0 && size > max {
+		return fmt.Errorf("%w: %d", ErrMaxOutput, max)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package go_template
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestLimits(t *testing.T) {
+	cases := []struct {
+		text   string
+		limits Limits
+		err    error
+	}{
+		{"{1 + 2 + 3 + 4}", Limits{MaxSteps: 5}, ErrMaxSteps},
+		{"{1 + 2} {3 + 4}", Limits{MaxSteps: 5}, ErrMaxSteps},
+		{"{round(1 + (2 + (3 + 4)), 1)}", Limits{MaxDepth: 3}, ErrMaxDepth},
+		{"abc {$a}", Limits{MaxOutputBytes: 6}, ErrMaxOutput},
+	}
+	for _, c := range cases {
+		tp, err := NewTemplateWithConfig(c.text, nil, &TemplateConfig{Limits: c.limits})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = tp.Render(`{"a": "xyz"}`)
+		if !errors.Is(err, c.err) {
+			t.Errorf("%s: expect %v, got %v", c.text, c.err, err)
+		}
+	}
+
+	tp, _ := NewTemplateWithConfig("{1 + 2 + 3 + 4} {round(1 + (2 + 3), 1)} {$a}",
+		nil, &TemplateConfig{Limits: Limits{MaxSteps: 20, MaxDepth: 4, MaxOutputBytes: 12}})
+	res, err := tp.Render(`{"a": "xyz"}`)
+	if err != nil || res != "10 6 xyz" {
+		t.Errorf("expect render within limits, got %s %v", res, err)
+	}
+}
+
+func TestLimits_Report(t *testing.T) {
+	tp, _ := NewTemplateWithConfig("ab {1 + 2} cd", nil, &TemplateConfig{Limits: Limits{MaxSteps: 1}})
+	report, err := tp.RenderDetailed(`{}`)
+	var te *TemplateError
+	if !errors.As(err, &te) || te.Kind != ErrKindLimit || te.Fragment != 1 {
+		t.Fatalf("expect limit error in fragment 1, got %v", err)
+	}
+	// $a is one step, 1 is the second
+	tp, _ = NewTemplateWithConfig("ab {$a.b} {1}", nil, &TemplateConfig{Limits: Limits{MaxSteps: 1}})
+	report, err = tp.RenderDetailed(`{"a": {"b": 1}}`)
+	if !errors.Is(err, ErrMaxSteps) || report.Output != "ab 1 " || len(report.Fragments) != 4 {
+		t.Errorf("expect partial report, got %q %v", report.Output, err)
+	}
+}
+
+func TestRenderContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	engine := NewTemplateEngine()
+	if err := engine.FnMgr.RegisterFunc("cancel", func(_ *TemplateConfig, _ []interface{}) (interface{}, error) {
+		cancel()
+		return decimal.NewFromInt(1), nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	tp, _ := NewTemplate("{cancel()} {1}", engine)
+	report, err := tp.RenderDetailedContext(ctx, "{}", nil)
+	if !errors.Is(err, context.Canceled) || report.Output != "1" {
+		t.Errorf("expect cancelled after first fragment, got %q %v", report.Output, err)
+	}
+
+	// checked while evaluating long expressions
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	tp, _ = NewTemplate("{cancel() + "+strings.Repeat("1 + ", 100)+"1}", engine)
+	if _, err := tp.RenderContext(ctx, "{}"); !errors.Is(err, context.Canceled) {
+		t.Errorf("expect cancelled, got %v", err)
+	}
+}
+
+func TestDivisionByZero(t *testing.T) {
+	// templates of untrusted authors must not crash the process
+	tp, _ := NewTemplateWithConfig("{$a / $b}", nil, &TemplateConfig{Strict: true, Limits: Limits{MaxSteps: 100}})
+	_, err := tp.Render(`{"a": 1, "b": 0}`)
+	var te *TemplateError
+	if !errors.As(err, &te) || te.Kind != ErrKindType || te.Column != 2 {
+		t.Errorf("expect division by zero, got %v", err)
+	}
+}
@@ -0,0 +1,234 @@
+// Package lint checks templates for mistakes the template parser accepts
+// silently or which only show up at render time.
+package lint
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	gt "github.com/CoinSummer/go-template"
+	"github.com/dop251/goja/ast"
+)
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// rules
+const (
+	RuleUnclosedBrace   = "unclosed-brace"
+	RuleSyntax          = "syntax"
+	RuleUnknownFunction = "unknown-function"
+	RuleFunctionArgs    = "function-args"
+	RuleBareIdentifier  = "bare-identifier"
+	RuleAlwaysFails     = "always-fails"
+	RuleUnreachable     = "unreachable-branch"
+	RuleLiteralBraces   = "literal-braces"
+)
+
+// Diagnostic is a problem found in a template
+type Diagnostic struct {
+	File     string   `json:"file,omitempty"`
+	Line     int      `json:"line"`   // 1-based
+	Column   int      `json:"column"` // 1-based
+	Severity Severity `json:"severity"`
+	Rule     string   `json:"rule"`
+	Message  string   `json:"message"`
+}
+
+func (d Diagnostic) String() string {
+	pos := fmt.Sprintf("%d:%d", d.Line, d.Column)
+	if d.File != "" {
+		pos = d.File + ":" + pos
+	}
+	return fmt.Sprintf("%s: %s: %s [%s]", pos, d.Severity, d.Message, d.Rule)
+}
+
+// Lint checks the template text, functions and operators are looked up in
+// engine, nil for the default engine
+func Lint(text string, engine *gt.TemplateEngine) []Diagnostic {
+	if engine == nil {
+		engine = gt.NewTemplateEngine()
+	}
+	l := &linter{text: text, engine: engine}
+	for _, segment := range gt.ScanTemplate(text) {
+		if segment.Expr {
+			l.checkExpr(segment)
+		}
+	}
+	return l.diagnostics
+}
+
+type linter struct {
+	text        string
+	engine      *gt.TemplateEngine
+	diagnostics []Diagnostic
+}
+
+// report a diagnostic at the byte offset of the template
+func (l *linter) report(offset int, severity Severity, rule string, format string, a ...interface{}) {
+	line, column := lineColumn(l.text, offset)
+	l.diagnostics = append(l.diagnostics, Diagnostic{
+		Line:     line,
+		Column:   column,
+		Severity: severity,
+		Rule:     rule,
+		Message:  fmt.Sprintf(format, a...),
+	})
+}
+
+func (l *linter) checkExpr(e gt.Segment) {
+	// offset of the expression content
+	base := e.Span.Start + 1
+	if !e.Closed {
+		l.report(e.Span.Start, SeverityError, RuleUnclosedBrace, "unclosed {, the rest of the template is rendered as plain text")
+		return
+	}
+	if strings.HasPrefix(e.Content, "{") && strings.HasSuffix(e.Content, "}") {
+		l.report(e.Span.Start, SeverityWarning, RuleLiteralBraces, "{{ starts an expression, literal braces can't be escaped")
+		return
+	}
+
+	f, err := gt.NewExprFragment(e.Content, l.engine.OperatorsMgr, l.engine.FnMgr)
+	if err != nil {
+		l.reportError(base, RuleSyntax, err)
+		return
+	}
+	f.Consts = l.engine.ConstMgr
+	s := &exprState{base: base}
+	if err := f.Validate(); err != nil {
+		// the expression can't be evaluated, but still walk it for other diagnostics
+		s.unsupported = true
+		rule := RuleFunctionArgs
+		var te *gt.TemplateError
+		if errors.As(err, &te) && te.Kind == gt.ErrKindUnknownFunction {
+			rule = RuleUnknownFunction
+		}
+		l.reportError(base, rule, err)
+	}
+
+	l.walk(f.Expression(), s)
+
+	// without variables the expression evaluates the same on every render,
+	// functions may have side effects so expressions calling them are not evaluated
+	if !s.hasVariables && !s.hasCalls && !s.unsupported {
+		if err := evalConstant(f, e.Content); err != nil {
+			l.reportError(base, RuleAlwaysFails, err)
+		}
+	}
+}
+
+// evalConstant evaluates an expression without variables, panics of
+// custom operators are reported as errors
+func evalConstant(f *gt.ExprFragment, content string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	f.Ctx = "{}"
+	_, err = f.EvalContent(content, &gt.TemplateConfig{TimeFormat: "2006-01-02 15:04:05Z07:00"})
+	return err
+}
+
+type exprState struct {
+	base         int // offset of the expression in the template
+	hasVariables bool
+	hasCalls     bool
+	unsupported  bool
+}
+
+func (l *linter) walk(node ast.Expression, s *exprState) {
+	offset := s.base + int(node.Idx0()) - 1
+	switch node := node.(type) {
+	case *ast.Identifier:
+		if strings.HasPrefix(node.Name.String(), "$") {
+			s.hasVariables = true
+		} else if _, ok := l.engine.ConstMgr.Get(node.Name.String()); !ok {
+			l.report(offset, SeverityError, RuleBareIdentifier, "%s is not a variable, variables start with $", node.Name)
+			s.unsupported = true
+		}
+	case *ast.CallExpression:
+		// the callee is a function name, checked by Validate
+		s.hasCalls = true
+		for _, arg := range node.ArgumentList {
+			l.walk(arg, s)
+		}
+	case *ast.DotExpression:
+		l.walk(node.Left, s)
+	case *ast.BracketExpression:
+		l.walk(node.Left, s)
+		l.walk(node.Member, s)
+	case *ast.BinaryExpression:
+		op := node.Operator.String()
+		if (op == "&&" || op == "||") && isLiteral(node.Left) {
+			l.report(offset, SeverityWarning, RuleUnreachable, "left side of %s is constant, one side is unreachable", op)
+		}
+		if l.engine.OperatorsMgr.GetFunc(op) == nil {
+			l.report(offset, SeverityError, RuleAlwaysFails, "operator not supported: %s", op)
+			s.unsupported = true
+		}
+		l.walk(node.Left, s)
+		l.walk(node.Right, s)
+	case *ast.ConditionalExpression:
+		l.report(offset, SeverityError, RuleAlwaysFails, "conditional expressions are not supported")
+		s.unsupported = true
+		if isLiteral(node.Test) {
+			l.report(offset, SeverityWarning, RuleUnreachable, "condition is constant, one branch is unreachable")
+		}
+		l.walk(node.Test, s)
+		l.walk(node.Consequent, s)
+		l.walk(node.Alternate, s)
+	case *ast.NumberLiteral, *ast.StringLiteral, *ast.BooleanLiteral:
+	default:
+		l.report(offset, SeverityError, RuleAlwaysFails, "expression not supported: %T", node)
+		s.unsupported = true
+	}
+}
+
+func (l *linter) reportError(base int, rule string, err error) {
+	offset := base
+	message := err.Error()
+	var te *gt.TemplateError
+	if errors.As(err, &te) {
+		message = te.Message
+		// messages of these kinds are the bare name
+		if te.Kind == gt.ErrKindUnknownFunction || te.Kind == gt.ErrKindUnknownVariable {
+			message = fmt.Sprintf("%s: %s", te.Kind, te.Message)
+		}
+		if te.Offset >= 0 {
+			offset += te.Offset
+		}
+	}
+	l.report(offset, SeverityError, rule, "%s", message)
+}
+
+func isLiteral(e ast.Expression) bool {
+	switch e.(type) {
+	case *ast.NumberLiteral, *ast.StringLiteral, *ast.BooleanLiteral, *ast.NullLiteral:
+		return true
+	default:
+		return false
+	}
+}
+
+// lineColumn converts a byte offset in text to 1-based line and column
+func lineColumn(text string, offset int) (int, int) {
+	if offset > len(text) {
+		offset = len(text)
+	}
+	line, column := 1, 1
+	for _, ch := range text[:offset] {
+		if ch == '\n' {
+			line += 1
+			column = 1
+		} else {
+			column += 1
+		}
+	}
+	return line, column
+}
@@ -0,0 +1,80 @@
+package lint
+
+import (
+	"testing"
+
+	gt "github.com/CoinSummer/go-template"
+)
+
+func TestLint(t *testing.T) {
+	text := "ok {$a.b + 1}\n{roud($a, 2)} {round($a)} {a + 1}\n{1 * 'x'} {true ? $a : $b} {{literal}} {$a.b"
+	expect := []string{
+		"2:2: error: unknown function: roud [unknown-function]",
+		"2:16: error: round expects 2 args, got 1 [function-args]",
+		"2:28: error: a is not a variable, variables start with $ [bare-identifier]",
+		"3:2: error: * with NaN: x [always-fails]",
+		"3:12: error: conditional expressions are not supported [always-fails]",
+		"3:12: warning: condition is constant, one branch is unreachable [unreachable-branch]",
+		"3:28: warning: {{ starts an expression, literal braces can't be escaped [literal-braces]",
+		"3:40: error: unclosed {, the rest of the template is rendered as plain text [unclosed-brace]",
+	}
+	diagnostics := Lint(text, nil)
+	if len(diagnostics) != len(expect) {
+		t.Fatalf("expect %d diagnostics, got %v", len(expect), diagnostics)
+	}
+	for i, d := range diagnostics {
+		if d.String() != expect[i] {
+			t.Errorf("expect %s, got %s", expect[i], d)
+		}
+	}
+}
+
+func TestLintSyntax(t *testing.T) {
+	diagnostics := Lint("a\n  {$a +\n 1a}", nil)
+	if len(diagnostics) != 1 || diagnostics[0].Rule != RuleSyntax || diagnostics[0].Line != 3 || diagnostics[0].Column != 2 {
+		t.Errorf("unexpected diagnostics: %v", diagnostics)
+	}
+}
+
+func TestLintConstants(t *testing.T) {
+	engine := gt.NewTemplateEngine()
+	_ = engine.RegisterConstant("WEI", 1)
+	if diagnostics := Lint("{$a / WEI} {WEI * 2}", engine); len(diagnostics) != 0 {
+		t.Errorf("unexpected diagnostics: %v", diagnostics)
+	}
+}
+
+func TestLintDoesNotCall(t *testing.T) {
+	engine := gt.NewTemplateEngine()
+	called := false
+	if err := engine.FnMgr.RegisterFunc("fetch", func(config *gt.TemplateConfig, args []interface{}) (interface{}, error) {
+		called = true
+		return nil, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	Lint("{fetch('x')}", engine)
+	if called {
+		t.Error("expect functions not called")
+	}
+}
+
+func TestLintDivisionByZero(t *testing.T) {
+	engine := gt.NewTemplateEngine()
+	engine.OperatorsMgr.RegisterFunc("%", func(arg1, arg2 interface{}) (interface{}, error) {
+		panic("mod by zero")
+	})
+	expect := []string{
+		"1:2: error: division by zero: 1 / 0 [always-fails]",
+		"1:10: error: panic: mod by zero [always-fails]",
+	}
+	diagnostics := Lint("{1 / 0} {1 % 0}", engine)
+	if len(diagnostics) != len(expect) {
+		t.Fatalf("expect %d diagnostics, got %v", len(expect), diagnostics)
+	}
+	for i, d := range diagnostics {
+		if d.String() != expect[i] {
+			t.Errorf("expect %s, got %s", expect[i], d)
+		}
+	}
+}
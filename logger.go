@@ -0,0 +1,11 @@
+package go_template
+
+// Logger is a structured logger, *slog.Logger satisfies it
+type Logger interface {
+	Warn(msg string, args ...interface{})
+}
+
+// NopLogger discards everything
+type NopLogger struct{}
+
+func (NopLogger) Warn(string, ...interface{}) {}
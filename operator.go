@@ -1,34 +1,44 @@
 package go_template
 
 import (
+	"sync"
+	"time"
+
 	"github.com/shopspring/decimal"
 )
 
 type IOperator func(arg1, arg2 interface{}) (interface{}, error)
 
+// OperatorsMgr holds binary operators, register and look them up through
+// its methods, they are safe for concurrent use
 type OperatorsMgr struct {
+	// Operators holds only the untyped operators registered on this manager,
+	// use Names and GetFunc to see all. Register through the methods, writing
+	// it directly isn't safe while templates render
 	Operators map[string]IOperator
+
+	mu     sync.RWMutex
+	typed  map[typedOperator]IOperator
+	parent *OperatorsMgr
 }
 
-func decimalize(arg interface{}) (decimal.Decimal, error) {
-	var a decimal.Decimal
-	var ok bool
-	var err error
-	a, ok = arg.(decimal.Decimal)
-	if !ok {
-		if s, ok := arg.(string); ok {
-			a, err = decimal.NewFromString(s)
-			if err != nil {
-				return decimal.Decimal{}, ErrFMsg("/ with NaN: %v", arg)
-			} else {
-				return a, nil
-			}
-		} else {
-			return decimal.Decimal{}, ErrFMsg("/ with NaN: %v", arg)
-		}
-	} else {
+type typedOperator struct {
+	name        string
+	left, right Kind
+}
+
+// decimalize converts an operand of op to decimal.Decimal
+func decimalize(op string, arg interface{}) (decimal.Decimal, error) {
+	if a, ok := toDecimal(arg); ok {
 		return a, nil
 	}
+	if s, ok := arg.(string); ok {
+		a, err := decimal.NewFromString(s)
+		if err == nil {
+			return a, nil
+		}
+	}
+	return decimal.Decimal{}, newTemplateError(ErrKindType, -1, "%s with NaN: %v", op, arg)
 }
 
 func NewOperatorsMgr() *OperatorsMgr {
@@ -37,24 +47,28 @@ func NewOperatorsMgr() *OperatorsMgr {
 			"/": func(arg1, arg2 interface{}) (interface{}, error) {
 				var a, b decimal.Decimal
 				var err error
-				a, err = decimalize(arg1)
+				a, err = decimalize("/", arg1)
 				if err != nil {
 					return nil, err
 				}
-				b, err = decimalize(arg2)
+				b, err = decimalize("/", arg2)
 				if err != nil {
 					return nil, err
 				}
+				// decimal panics dividing by zero
+				if b.IsZero() {
+					return nil, newTemplateError(ErrKindType, -1, "division by zero: %v / %v", arg1, arg2)
+				}
 				return a.Div(b), nil
 			},
 			"+": func(arg1, arg2 interface{}) (interface{}, error) {
 				var a, b decimal.Decimal
 				var err error
-				a, err = decimalize(arg1)
+				a, err = decimalize("+", arg1)
 				if err != nil {
 					return nil, err
 				}
-				b, err = decimalize(arg2)
+				b, err = decimalize("+", arg2)
 				if err != nil {
 					return nil, err
 				}
@@ -63,11 +77,11 @@ func NewOperatorsMgr() *OperatorsMgr {
 			"-": func(arg1, arg2 interface{}) (interface{}, error) {
 				var a, b decimal.Decimal
 				var err error
-				a, err = decimalize(arg1)
+				a, err = decimalize("-", arg1)
 				if err != nil {
 					return nil, err
 				}
-				b, err = decimalize(arg2)
+				b, err = decimalize("-", arg2)
 				if err != nil {
 					return nil, err
 				}
@@ -76,24 +90,151 @@ func NewOperatorsMgr() *OperatorsMgr {
 			"*": func(arg1, arg2 interface{}) (interface{}, error) {
 				var a, b decimal.Decimal
 				var err error
-				a, err = decimalize(arg1)
+				a, err = decimalize("*", arg1)
 				if err != nil {
 					return nil, err
 				}
-				b, err = decimalize(arg2)
+				b, err = decimalize("*", arg2)
 				if err != nil {
 					return nil, err
 				}
 				return a.Mul(b), nil
 			},
 		},
+		typed: map[typedOperator]IOperator{
+			{"+", KindString, KindString}: func(arg1, arg2 interface{}) (interface{}, error) {
+				return arg1.(string) + arg2.(string), nil
+			},
+			// numeric strings add as numbers, other strings concatenate with numbers
+			{"+", KindString, KindNumber}: func(arg1, arg2 interface{}) (interface{}, error) {
+				return arg1.(string) + NewValue(arg2).String(), nil
+			},
+			{"+", KindNumber, KindString}: func(arg1, arg2 interface{}) (interface{}, error) {
+				return NewValue(arg1).String() + arg2.(string), nil
+			},
+			{"+", KindTime, KindDuration}: func(arg1, arg2 interface{}) (interface{}, error) {
+				return arg1.(time.Time).Add(arg2.(time.Duration)), nil
+			},
+			{"+", KindDuration, KindTime}: func(arg1, arg2 interface{}) (interface{}, error) {
+				return arg2.(time.Time).Add(arg1.(time.Duration)), nil
+			},
+			{"+", KindDuration, KindDuration}: func(arg1, arg2 interface{}) (interface{}, error) {
+				return arg1.(time.Duration) + arg2.(time.Duration), nil
+			},
+			{"-", KindTime, KindDuration}: func(arg1, arg2 interface{}) (interface{}, error) {
+				return arg1.(time.Time).Add(-arg2.(time.Duration)), nil
+			},
+			{"-", KindTime, KindTime}: func(arg1, arg2 interface{}) (interface{}, error) {
+				return arg1.(time.Time).Sub(arg2.(time.Time)), nil
+			},
+			{"-", KindDuration, KindDuration}: func(arg1, arg2 interface{}) (interface{}, error) {
+				return arg1.(time.Duration) - arg2.(time.Duration), nil
+			},
+		},
+	}
+}
+
+// Fork creates a manager whose lookups fall back to f, operators
+// registered on it shadow the ones of f without affecting it
+func (f *OperatorsMgr) Fork() *OperatorsMgr {
+	return &OperatorsMgr{
+		Operators: map[string]IOperator{},
+		parent:    f,
 	}
 }
 
+// RegisterFunc registers fn for operands of any kind, used when no typed
+// implementation matches, inherited ones included
 func (f *OperatorsMgr) RegisterFunc(name string, fn IOperator) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
 	f.Operators[name] = fn
 }
 
+// RegisterTyped registers fn for operands of the left and right kinds, see
+// KindOf. KindAny matches every kind
+func (f *OperatorsMgr) RegisterTyped(name string, left, right Kind, fn IOperator) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.typed == nil {
+		f.typed = map[typedOperator]IOperator{}
+	}
+	f.typed[typedOperator{name, left, right}] = fn
+}
+
+// GetFunc returns the operator dispatching on the kinds of its operands,
+// nil if name is not registered
 func (f *OperatorsMgr) GetFunc(name string) IOperator {
-	return f.Operators[name]
+	if !f.has(name) {
+		return nil
+	}
+	return func(arg1, arg2 interface{}) (interface{}, error) {
+		left, right := KindOf(arg1), KindOf(arg2)
+		fn := f.resolve(name, left, right)
+		if fn == nil {
+			return nil, newTemplateError(ErrKindType, -1, "%s not supported for %s and %s", name, left, right)
+		}
+		return fn(arg1, arg2)
+	}
+}
+
+func (f *OperatorsMgr) has(name string) bool {
+	for m := f; m != nil; m = m.parent {
+		m.mu.RLock()
+		_, ok := m.Operators[name]
+		for op := range m.typed {
+			ok = ok || op.name == name
+		}
+		m.mu.RUnlock()
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve finds the implementation for the operand kinds, the most specific
+// typed one first and the untyped one last, whether inherited or not. Of
+// operators registered for the same kinds, the ones of f shadow inherited ones
+func (f *OperatorsMgr) resolve(name string, left, right Kind) IOperator {
+	for _, op := range []typedOperator{
+		{name, left, right},
+		{name, left, KindAny},
+		{name, KindAny, right},
+		{name, KindAny, KindAny},
+	} {
+		for m := f; m != nil; m = m.parent {
+			m.mu.RLock()
+			fn := m.typed[op]
+			m.mu.RUnlock()
+			if fn != nil {
+				return fn
+			}
+		}
+	}
+	for m := f; m != nil; m = m.parent {
+		m.mu.RLock()
+		fn := m.Operators[name]
+		m.mu.RUnlock()
+		if fn != nil {
+			return fn
+		}
+	}
+	return nil
+}
+
+// Names returns the sorted names of all operators, inherited ones included
+func (f *OperatorsMgr) Names() []string {
+	seen := map[string]bool{}
+	for m := f; m != nil; m = m.parent {
+		m.mu.RLock()
+		for name := range m.Operators {
+			seen[name] = true
+		}
+		for op := range m.typed {
+			seen[op.name] = true
+		}
+		m.mu.RUnlock()
+	}
+	return sortedKeys(seen)
 }
@@ -0,0 +1,87 @@
+package go_template
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestOperators_Typed(t *testing.T) {
+	cases := map[string]string{
+		`"ETH" + "/USD"`: "ETH/USD",
+		`$a + $b`:        "3",
+		`$a + 1`:         "2",
+		`"0x" + $b`:      "0x2",
+		`$a + " USD"`:    "1 USD",
+		`"#" + 1.5`:      "#1.5",
+		`formatTime(time("2021-01-01T00:00:00Z") + duration("1h30m"), 0)`: "2021-01-01 01:30:00Z",
+		`formatTime(time(1609459200) - duration("24h"), 0)`:               "2020-12-31 00:00:00Z",
+		`time("2021-01-02T00:00:00Z") - time("2021-01-01T12:00:00Z")`:     "12h0m0s",
+		`duration("1h") + duration("1m")`:                                 "1h1m0s",
+	}
+	for src, expect := range cases {
+		tp, err := NewTemplateWithConfig("{"+src+"}", nil, config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := tp.RenderWithConfig(`{"a": "1", "b": "2"}`, &TemplateConfig{TimeFormat: config.TimeFormat, Strict: true})
+		if err != nil || res != expect {
+			t.Errorf("%s: expect %s, got %s %v", src, expect, res, err)
+		}
+	}
+}
+
+func TestOperators_RegisterTyped(t *testing.T) {
+	engine := NewTemplateEngine()
+	engine.OperatorsMgr.RegisterTyped("*", KindString, KindNumber, func(arg1, arg2 interface{}) (interface{}, error) {
+		n, _ := decimalize("*", arg2)
+		return strings.Repeat(arg1.(string), int(n.IntPart())), nil
+	})
+	engine.OperatorsMgr.RegisterTyped("%", KindNumber, KindNumber, func(arg1, arg2 interface{}) (interface{}, error) {
+		a, _ := decimalize("%", arg1)
+		b, _ := decimalize("%", arg2)
+		return a.Mod(b), nil
+	})
+	tp, _ := NewTemplate("{'ab' * 3} {2 * 3} {7 % 4}", engine)
+	if res, err := tp.Render("{}"); err != nil || res != "ababab 6 3" {
+		t.Errorf("expect ababab 6 3, got %s %v", res, err)
+	}
+
+	op := engine.OperatorsMgr.GetFunc("%")
+	_, err := op("a", decimal.NewFromInt(1))
+	var te *TemplateError
+	if !errors.As(err, &te) || te.Message != "% not supported for string and number" {
+		t.Errorf("expect unsupported operands, got %v", err)
+	}
+
+	// typed operators of a child shadow the parent's
+	child := engine.Fork()
+	child.OperatorsMgr.RegisterTyped("+", KindString, KindString, func(arg1, arg2 interface{}) (interface{}, error) {
+		return arg1.(string) + " " + arg2.(string), nil
+	})
+	for e, expect := range map[*TemplateEngine]string{engine: "ab 3", child: "a b 3"} {
+		tp, _ := NewTemplate("{'a' + 'b'} {1 + 2}", e)
+		if res, err := tp.Render("{}"); err != nil || res != expect {
+			t.Errorf("expect %s, got %s %v", expect, res, err)
+		}
+	}
+
+	// untyped operators don't shadow typed ones, on root and child engines alike
+	sum := func(arg1, arg2 interface{}) (interface{}, error) {
+		a, _ := decimalize("+", arg1)
+		b, _ := decimalize("+", arg2)
+		return a.Add(b).Add(decimal.NewFromInt(100)), nil
+	}
+	root := NewTemplateEngine()
+	child = root.Fork()
+	root.OperatorsMgr.RegisterFunc("+", sum)
+	child.OperatorsMgr.RegisterFunc("+", sum)
+	for _, e := range []*TemplateEngine{root, child} {
+		tp, _ := NewTemplate("{'a' + 'b'} {1 + 2}", e)
+		if res, err := tp.Render("{}"); err != nil || res != "ab 103" {
+			t.Errorf("expect ab 103, got %s %v", res, err)
+		}
+	}
+}
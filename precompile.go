@@ -0,0 +1,102 @@
+package go_template
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// PrecompiledVersion is bumped whenever the IR or its evaluation changes,
+// precompiled templates of other versions must be compiled again from source
+const PrecompiledVersion = 1
+
+// ErrPrecompiledVersion is returned loading templates precompiled by another engine version
+var ErrPrecompiledVersion = errors.New("precompiled template version mismatch")
+
+type precompiledTemplate struct {
+	Version   int                   `json:"version"`
+	Text      string                `json:"text"`
+	Fragments []precompiledFragment `json:"fragments"`
+}
+
+type precompiledFragment struct {
+	Start int   `json:"s"`
+	End   int   `json:"e"`
+	Node  *Node `json:"x,omitempty"` // nil for plain text
+}
+
+// Precompile encodes the parsed template, LoadTemplate restores it without parsing
+func (t *Template) Precompile() ([]byte, error) {
+	p := precompiledTemplate{
+		Version: PrecompiledVersion,
+		Text:    t.templateText,
+	}
+	for i, fragment := range t.parsedTemplate {
+		pf := precompiledFragment{Start: t.spans[i].Start, End: t.spans[i].End}
+		if f, ok := fragment.(*ExprFragment); ok {
+			pf.Node = f.Node
+		}
+		p.Fragments = append(p.Fragments, pf)
+	}
+	return json.Marshal(p)
+}
+
+// LoadTemplate restores a template encoded by Precompile, function calls are
+// checked against engine as NewTemplate does. ErrPrecompiledVersion is returned
+// for templates precompiled by another engine version
+func LoadTemplate(data []byte, engine *TemplateEngine, config *TemplateConfig) (*Template, error) {
+	var header struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, fmt.Errorf("bad precompiled template: %w", err)
+	}
+	if header.Version != PrecompiledVersion {
+		return nil, fmt.Errorf("%w: got %d, expect %d", ErrPrecompiledVersion, header.Version, PrecompiledVersion)
+	}
+	var p precompiledTemplate
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("bad precompiled template: %w", err)
+	}
+
+	t := newTemplate(p.Text, engine, config)
+	for i, pf := range p.Fragments {
+		if pf.Start < 0 || pf.End > len(p.Text) || pf.Start > pf.End {
+			return nil, fmt.Errorf("bad precompiled template: fragment %d out of range", i)
+		}
+		span := Span{Start: pf.Start, End: pf.End}
+		raw := p.Text[pf.Start:pf.End]
+		if pf.Node == nil {
+			// plain text starts with { only if the brace is unclosed, which
+			// the parser drops
+			t.parsedTemplate = append(t.parsedTemplate, NewPlainFragment(strings.TrimPrefix(raw, "{")))
+			t.spans = append(t.spans, span)
+			continue
+		}
+		if len(raw) < 2 {
+			return nil, fmt.Errorf("bad precompiled template: fragment %d is not an expression", i)
+		}
+		if err := pf.Node.check(); err != nil {
+			return nil, fmt.Errorf("bad precompiled template: fragment %d: %w", i, err)
+		}
+		// strip {}
+		f := NewExprFragmentFromNode(raw[1:len(raw)-1], pf.Node, t.engine.OperatorsMgr, t.engine.FnMgr)
+		f.Consts = t.engine.ConstMgr
+		f.Hooks = t.engine.Hooks
+		err := f.Validate()
+		if err == nil {
+			err = f.CheckCapabilities(t.TemplateConfig.Capabilities)
+		}
+		if err != nil {
+			var te *TemplateError
+			if errors.As(err, &te) {
+				te.locate(t.templateText, i, span)
+			}
+			return nil, err
+		}
+		t.parsedTemplate = append(t.parsedTemplate, f)
+		t.spans = append(t.spans, span)
+	}
+	return t, nil
+}
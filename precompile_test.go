@@ -0,0 +1,102 @@
+package go_template
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrecompile(t *testing.T) {
+	text := "a {$a.b / 1e18} b {round($a['c.d'], 1)}\n{$a.x} {timezone($ts, 8)}"
+	ctx := `{"a": {"b": "8912239900000000000", "c.d": 1.26}, "ts": 1665000000}`
+	tp, err := NewTemplate(text, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := tp.Precompile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadTemplate(data, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Text() != text {
+		t.Errorf("unexpected text: %s", loaded.Text())
+	}
+
+	expect, _ := tp.RenderDetailed(ctx)
+	got, _ := loaded.RenderDetailed(ctx)
+	assert.Equal(t, expect.Output, got.Output)
+	assert.Equal(t, "a 8.91 b 1.3\n{$a.x} 2022-10-06 04:00:00+08:00", got.Output)
+	var te *TemplateError
+	if !errors.As(got.Fragments[5].Err, &te) || te.Line != 2 || te.Column != 5 {
+		t.Errorf("unexpected error: %v", got.Fragments[5].Err)
+	}
+	assert.Equal(t, tp.Variables(), loaded.Variables())
+	assert.Equal(t, tp.Functions(), loaded.Functions())
+}
+
+func TestLoadTemplateErrors(t *testing.T) {
+	engine := NewTemplateEngine()
+	if err := engine.FnMgr.RegisterFunc("double", func(_ *TemplateConfig, args []interface{}) (interface{}, error) {
+		return args[0], nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	tp, err := NewTemplate("{double($a)}", engine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := tp.Precompile()
+
+	// functions are checked against the loading engine
+	_, err = LoadTemplate(data, nil, nil)
+	var te *TemplateError
+	if !errors.As(err, &te) || te.Kind != ErrKindUnknownFunction {
+		t.Errorf("expect unknown function, got %v", err)
+	}
+
+	var raw map[string]interface{}
+	_ = json.Unmarshal(data, &raw)
+	raw["version"] = PrecompiledVersion + 1
+	data, _ = json.Marshal(raw)
+	_, err = LoadTemplate(data, engine, nil)
+	if !errors.Is(err, ErrPrecompiledVersion) {
+		t.Errorf("expect version mismatch, got %v", err)
+	}
+
+	_, err = LoadTemplate([]byte(`{"version": 1, "text": "ab", "fragments": [{"s": 0, "e": 3}]}`), engine, nil)
+	if err == nil || !strings.Contains(err.Error(), "out of range") {
+		t.Errorf("expect out of range, got %v", err)
+	}
+
+	for _, node := range []string{`{"o": "dot", "n": "b"}`, `{"o": "bin", "n": "+", "a": [null, {"o": "num", "v": "1"}]}`, `{"o": "loop"}`} {
+		data := `{"version": 1, "text": "{$a.b}", "fragments": [{"s": 0, "e": 6, "x": ` + node + `}]}`
+		if _, err = LoadTemplate([]byte(data), engine, nil); err == nil || !strings.Contains(err.Error(), "bad precompiled template") {
+			t.Errorf("%s: expect bad template, got %v", node, err)
+		}
+	}
+}
+
+func TestPrecompile_PlainText(t *testing.T) {
+	for _, text := range []string{"a {abc", "a {b{c}", "{", "a } {$a} {"} {
+		tp, err := NewTemplate(text, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := tp.Precompile()
+		loaded, err := LoadTemplate(data, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expect, _ := tp.Render(`{"a": 1}`)
+		got, _ := loaded.Render(`{"a": 1}`)
+		if got != expect {
+			t.Errorf("%q: expect %q, got %q", text, expect, got)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package go_template
+
+// Formatting describes how an evaluated value was turned into output text
+type Formatting string
+
+const (
+	FormattingNone   Formatting = "none"   // value copied to output as is
+	FormattingPlain  Formatting = "plain"  // plain text, `{{` and `}}` unescaped
+	FormattingNumber Formatting = "number" // rounded with thousand separators
+	FormattingJSON   Formatting = "json"   // marshaled to json
+	FormattingRaw    Formatting = "raw"    // evaluation failed, raw expression written back
+)
+
+// Span is a byte range [Start, End)
+type Span struct {
+	Start int
+	End   int
+}
+
+// FragmentResult is what happened to a single fragment during a render
+type FragmentResult struct {
+	Index      int
+	Raw        string      // fragment content, expressions without {}
+	IsExpr     bool        // false for plain text
+	Source     Span        // range in the template text, including {}
+	Output     Span        // range in the rendered output
+	Value      interface{} // evaluated value before formatting
+	Type       string      // go type of Value
+	Formatting Formatting
+	Text       string // text written to the output
+	Err        error
+}
+
+// RenderReport is the output of a render along with per fragment diagnostics
+type RenderReport struct {
+	Output    string
+	Fragments []*FragmentResult
+}
+
+// Failed returns fragments which failed to evaluate
+func (r *RenderReport) Failed() []*FragmentResult {
+	var failed []*FragmentResult
+	for _, f := range r.Fragments {
+		if f.Err != nil {
+			failed = append(failed, f)
+		}
+	}
+	return failed
+}
@@ -0,0 +1,156 @@
+package go_template
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	"github.com/tidwall/gjson"
+)
+
+// VariableResolver resolves $name variables absent from the render context,
+// like a user profile fetched only if a template references it. ok is false
+// if the resolver doesn't know name. Results are memoized per render
+type VariableResolver interface {
+	Resolve(ctx context.Context, name string) (value interface{}, ok bool, err error)
+}
+
+// VariableResolverFunc adapts a function to VariableResolver
+type VariableResolverFunc func(ctx context.Context, name string) (interface{}, bool, error)
+
+func (fn VariableResolverFunc) Resolve(ctx context.Context, name string) (interface{}, bool, error) {
+	return fn(ctx, name)
+}
+
+type resolvedVariable struct {
+	node *ctxNode
+	err  error // error of the resolver, each lookup gets its own TemplateError
+}
+
+// variable looks up name in the scopes and the context, then asks the
+// resolvers in order
+func (s *evalState) variable(name string) (*ctxNode, error) {
+	if r, ok := s.resolved[name]; ok {
+		return r.node, resolveError(name, r.err)
+	}
+	var r resolvedVariable
+	if scope, ok := s.scopes[name]; ok {
+		r.node = scopeNode(scope)
+	} else if node := s.context().child(name); node != nil {
+		return node, nil
+	} else {
+		r = s.resolve(name)
+	}
+	if s.resolved == nil {
+		s.resolved = map[string]resolvedVariable{}
+	}
+	s.resolved[name] = r
+	return r.node, resolveError(name, r.err)
+}
+
+// resolveError wraps the error of a resolver, errors are located by the
+// fragment so a memoized error can't be shared
+func resolveError(name string, err error) error {
+	if err == nil {
+		return nil
+	}
+	te := newTemplateError(ErrKindUnknownVariable, -1, "failed resolve %s: %s", name, err)
+	te.Err = err
+	return te
+}
+
+// resolve asks the resolvers for name until one knows it
+func (s *evalState) resolve(name string) resolvedVariable {
+	for _, resolver := range s.resolvers {
+		value, ok, err := resolver.Resolve(s.rctx, name)
+		if err != nil {
+			return resolvedVariable{err: err}
+		}
+		if ok {
+			return resolvedVariable{node: nativeNode(value)}
+		}
+	}
+	return resolvedVariable{}
+}
+
+// schemaContext returns the context validated against schema, with the top
+// level variables of schema taken from the scopes, or resolved if absent
+// from the context
+func (s *evalState) schemaContext(schema *Schema) (string, error) {
+	root := s.context()
+	// an empty context is an empty object
+	if (len(s.scopes) == 0 && len(s.resolvers) == 0) || (root.value.Exists() && !root.value.IsObject()) {
+		return s.ctx, nil
+	}
+	merged := map[string]json.RawMessage{}
+	root.value.ForEach(func(k, v gjson.Result) bool {
+		if _, ok := merged[k.String()]; !ok {
+			merged[k.String()] = json.RawMessage(v.Raw)
+		}
+		return true
+	})
+	keys := make([]string, 0, len(schema.Properties))
+	for key := range schema.Properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	resolved := false
+	for _, key := range keys {
+		if _, scoped := s.scopes[key]; !scoped && root.child(key) != nil {
+			continue
+		}
+		node, err := s.variable(key)
+		if err != nil {
+			return "", err
+		}
+		if node == nil {
+			continue
+		}
+		raw, err := node.json()
+		if err != nil {
+			return "", err
+		}
+		merged[key] = raw
+		resolved = true
+	}
+	if !resolved {
+		return s.ctx, nil
+	}
+	j, err := json.Marshal(merged)
+	return string(j), err
+}
+
+// json encodes the node, numbers of native nodes as json numbers
+func (c *ctxNode) json() (json.RawMessage, error) {
+	if !c.native {
+		return json.RawMessage(c.value.Raw), nil
+	}
+	return json.Marshal(jsonNumbers(c.raw))
+}
+
+// jsonNumbers converts numbers in Go values to json.Number, decimal.Decimal
+// is encoded as a string otherwise
+func jsonNumbers(value interface{}) interface{} {
+	if v, ok := value.(Value); ok {
+		value = v.Interface()
+	}
+	if d, ok := toDecimal(value); ok {
+		return json.Number(d.String())
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[key] = jsonNumbers(item)
+		}
+		return m
+	case []interface{}:
+		items := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			items = append(items, jsonNumbers(item))
+		}
+		return items
+	default:
+		return value
+	}
+}
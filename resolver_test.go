@@ -0,0 +1,98 @@
+package go_template
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type profileKey struct{}
+
+func TestVariableResolver(t *testing.T) {
+	engine := NewTemplateEngine()
+	calls := map[string]int{}
+	engine.AddResolver(VariableResolverFunc(func(ctx context.Context, name string) (interface{}, bool, error) {
+		calls[name]++
+		switch name {
+		case "user":
+			return map[string]interface{}{
+				"name":   ctx.Value(profileKey{}),
+				"joined": time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+				"tags":   []interface{}{"vip"},
+			}, true, nil
+		case "fee":
+			return int64(5), true, nil
+		case "broken":
+			return nil, false, errors.New("timeout")
+		}
+		return nil, false, nil
+	}))
+
+	tp, err := NewTemplate("{$user.name} {$user.tags[0]} {formatTime($user.joined, 0)} {$fee + $amount} {$user.name} {$a}", engine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.WithValue(context.Background(), profileKey{}, "alice")
+	res, err := tp.RenderContext(ctx, `{"amount": 1, "fee": null}`)
+	if expect := "alice vip 2021-01-01 00:00:00Z 6 alice {$a}"; err != nil || res != expect {
+		t.Errorf("expect %s, got %s %v", expect, res, err)
+	}
+	// memoized per render, values of the context come first
+	if calls["user"] != 1 || calls["fee"] != 1 || calls["amount"] != 0 {
+		t.Errorf("unexpected calls %v", calls)
+	}
+
+	tp, _ = NewTemplate("{$broken}\n\n   {$broken}", engine)
+	report, _ := tp.RenderDetailed("{}")
+	if err := report.Fragments[0].Err; err == nil || errors.Unwrap(err) == nil || errors.Unwrap(err).Error() != "timeout" {
+		t.Errorf("expect resolver error, got %v", err)
+	}
+	// the memoized error is located in each fragment
+	for i, pos := range map[int][2]int{0: {1, 2}, 2: {3, 5}} {
+		var te *TemplateError
+		if !errors.As(report.Fragments[i].Err, &te) || te.Fragment != i || te.Line != pos[0] || te.Column != pos[1] {
+			t.Errorf("fragment %d: unexpected error %+v", i, te)
+		}
+	}
+	if calls["broken"] != 1 {
+		t.Errorf("expect one call, got %d", calls["broken"])
+	}
+}
+
+func TestVariableResolver_ValidateContext(t *testing.T) {
+	engine := NewTemplateEngine()
+	calls := 0
+	engine.AddResolver(VariableResolverFunc(func(ctx context.Context, name string) (interface{}, bool, error) {
+		calls++
+		if name == "profile" {
+			return map[string]interface{}{"name": "alice", "age": 30}, true, nil
+		}
+		return nil, false, nil
+	}))
+	tp, err := NewTemplateWithConfig("{$profile.name} {$profile.age + 1} {$amount}", engine, &TemplateConfig{ValidateContext: true, Strict: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := tp.InferSchema()
+	schema.Properties["profile"].Properties["name"].Type = SchemaString
+	if err := tp.SetSchema(schema); err != nil {
+		t.Fatal(err)
+	}
+	res, err := tp.Render(`{"amount": 2}`)
+	if err != nil || res != "alice 31 2" || calls != 1 {
+		t.Errorf("expect alice 31 2 with one call, got %s %v %d", res, err, calls)
+	}
+
+	// resolved values are validated like the context
+	schema.Properties["profile"].Properties["name"].Type = SchemaNumber
+	_, err = tp.Render(`{"amount": 2}`)
+	var se *SchemaError
+	if !errors.As(err, &se) || se.Issues[0].Path != "profile.name" {
+		t.Errorf("expect profile.name mismatch, got %v", err)
+	}
+	_, err = tp.Render(``)
+	if !errors.As(err, &se) || se.Issues[0].Path != "amount" {
+		t.Errorf("expect amount missing, got %v", err)
+	}
+}
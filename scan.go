@@ -0,0 +1,43 @@
+package go_template
+
+// Segment is a piece of template source, plain text or an {expression}
+type Segment struct {
+	Span    Span   // range in the template, including {}
+	Expr    bool   // false for plain text
+	Closed  bool   // false for an expression missing its closing }, rendered as plain text
+	Content string // plain text, or the expression without {}
+}
+
+// ScanTemplate splits template source the way the parser does, without parsing expressions
+func ScanTemplate(text string) []Segment {
+	var segments []Segment
+	depth := 0
+	start := 0
+	for i, ch := range text {
+		switch ch {
+		case '{':
+			if depth == 0 {
+				if i > start {
+					segments = append(segments, Segment{Span: Span{start, i}, Content: text[start:i]})
+				}
+				start = i
+			}
+			depth += 1
+		case '}':
+			if depth == 0 {
+				continue
+			}
+			depth -= 1
+			if depth == 0 {
+				segments = append(segments, Segment{Span: Span{start, i + 1}, Expr: true, Closed: true, Content: text[start+1 : i]})
+				start = i + 1
+			}
+		}
+	}
+	if depth > 0 {
+		segments = append(segments, Segment{Span: Span{start, len(text)}, Expr: true, Content: text[start+1:]})
+	} else if start < len(text) {
+		segments = append(segments, Segment{Span: Span{start, len(text)}, Content: text[start:]})
+	}
+	return segments
+}
@@ -0,0 +1,317 @@
+package go_template
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// Schema is the subset of JSON Schema used to describe template contexts
+type Schema struct {
+	Type       string             `json:"type,omitempty"` // object, array, number, string or boolean
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	Required   []string           `json:"required,omitempty"`
+	Items      *Schema            `json:"items,omitempty"`
+}
+
+const (
+	SchemaObject  = "object"
+	SchemaArray   = "array"
+	SchemaNumber  = "number"
+	SchemaInteger = "integer"
+	SchemaString  = "string"
+	SchemaBoolean = "boolean"
+)
+
+// InferSchema describes the context the template expects: objects for
+// .key access, arrays for [index] access, and type hints from arithmetic
+// operators and function signatures
+func (t *Template) InferSchema() *Schema {
+	root := &Schema{Type: SchemaObject}
+	for _, fragment := range t.parsedTemplate {
+		f, ok := fragment.(*ExprFragment)
+		if !ok {
+			continue
+		}
+		f.inferSchema(root, f.Node, "")
+	}
+	return root
+}
+
+var arithmeticOperators = map[string]bool{
+	"+": true,
+	"-": true,
+	"*": true,
+	"/": true,
+}
+
+// inferSchema adds variables used in n to root, hint is the type expected by the parent expression
+func (f *ExprFragment) inferSchema(root *Schema, n *Node, hint string) {
+	if _, segments, ok := pathSegments(n); ok {
+		root.addPath(segments, hint)
+		for _, member := range dynamicMembers(n) {
+			f.inferSchema(root, member, "")
+		}
+		return
+	}
+	switch n.Op {
+	case OpBinary:
+		operandHint := ""
+		if arithmeticOperators[n.Name] {
+			operandHint = SchemaNumber
+		}
+		// + concatenates strings, only literals tell which one it is
+		if n.Name == "+" {
+			switch {
+			case n.Args[0].Op == OpString || n.Args[1].Op == OpString:
+				operandHint = SchemaString
+			case n.Args[0].Op != OpNumber && n.Args[1].Op != OpNumber:
+				operandHint = ""
+			}
+		}
+		f.inferSchema(root, n.Args[0], operandHint)
+		f.inferSchema(root, n.Args[1], operandHint)
+	case OpCall:
+		sig := f.FnMgr.GetSignature(n.Name)
+		for i, arg := range n.Args {
+			argHint := ""
+			if sig != nil {
+				argHint = argKindSchema[sig.ArgKind(i)]
+			}
+			f.inferSchema(root, arg, argHint)
+		}
+	default:
+		for _, arg := range n.Args {
+			f.inferSchema(root, arg, "")
+		}
+	}
+}
+
+var argKindSchema = map[ArgKind]string{
+	ArgNumber: SchemaNumber,
+	ArgString: SchemaString,
+	ArgBool:   SchemaBoolean,
+}
+
+// addPath adds the access chain to the object schema s, typing the leaf with hint
+func (s *Schema) addPath(segments []pathSegment, hint string) {
+	node := s
+	for i, segment := range segments {
+		var child *Schema
+		switch segment.Kind {
+		case segmentKey:
+			node.setType(SchemaObject)
+			if node.Properties == nil {
+				node.Properties = map[string]*Schema{}
+			}
+			child = node.Properties[segment.Key]
+			if child == nil {
+				child = &Schema{}
+				node.Properties[segment.Key] = child
+				node.Required = append(node.Required, segment.Key)
+			}
+		default:
+			node.setType(SchemaArray)
+			if node.Items == nil {
+				node.Items = &Schema{}
+			}
+			child = node.Items
+		}
+		if i == len(segments)-1 && hint != "" {
+			child.setType(hint)
+		}
+		node = child
+	}
+}
+
+// setType keeps the first type inferred
+func (s *Schema) setType(tp string) {
+	if s.Type == "" {
+		s.Type = tp
+	}
+}
+
+// SchemaIssue is a path the template references or the schema requires
+// that is absent or wrongly typed
+type SchemaIssue struct {
+	Path     string
+	Problem  string // missing or type
+	Expected string
+	Got      string
+	Sites    []Position // where the template references Path, if known
+}
+
+const (
+	IssueMissing = "missing"
+	IssueType    = "type"
+)
+
+func (i SchemaIssue) String() string {
+	if i.Problem == IssueMissing {
+		return i.Path + ": missing"
+	}
+	return i.Path + ": expect " + i.Expected + ", got " + i.Got
+}
+
+// SchemaError lists issues found checking a template or context against a schema
+type SchemaError struct {
+	Issues []SchemaIssue
+}
+
+func (e *SchemaError) Error() string {
+	msg := "schema mismatch:"
+	for _, issue := range e.Issues {
+		msg += " " + issue.String() + ";"
+	}
+	return msg
+}
+
+// CheckSchema reports $paths referenced by the template which are absent
+// or wrongly typed in schema
+func (t *Template) CheckSchema(schema *Schema) []SchemaIssue {
+	var issues []SchemaIssue
+	compareSchema(t.InferSchema(), schema, "", &issues)
+	refs := t.Variables()
+	for i := range issues {
+		for _, ref := range refs {
+			// issue paths of arrays are [*]
+			name := arrayIndex.ReplaceAllString(ref.Name, "[*]")
+			if name == issues[i].Path || isSubPath(name, issues[i].Path) {
+				issues[i].Sites = append(issues[i].Sites, ref.Sites...)
+			}
+		}
+	}
+	return issues
+}
+
+// SetSchema declares the context schema of the template, it is rejected
+// if the template doesn't fit it. The context is checked against the
+// schema on render if TemplateConfig.ValidateContext is set
+func (t *Template) SetSchema(schema *Schema) error {
+	if issues := t.CheckSchema(schema); len(issues) > 0 {
+		return &SchemaError{Issues: issues}
+	}
+	t.Schema = schema
+	return nil
+}
+
+var arrayIndex = regexp.MustCompile(`\[\d+\]`)
+
+func isSubPath(path, prefix string) bool {
+	if len(path) <= len(prefix) || path[:len(prefix)] != prefix {
+		return false
+	}
+	next := path[len(prefix)]
+	return next == '.' || next == '['
+}
+
+// compareSchema checks the inferred schema against the declared one
+func compareSchema(inferred, declared *Schema, path string, issues *[]SchemaIssue) {
+	if inferred.Type != "" && declared.Type != "" && !typeAccepts(declared.Type, inferred.Type) {
+		*issues = append(*issues, SchemaIssue{Path: path, Problem: IssueType, Expected: inferred.Type, Got: declared.Type})
+		return
+	}
+	for _, key := range inferred.Required {
+		child := pathSegment{Kind: segmentKey, Key: key}.join(path)
+		prop := declared.Properties[key]
+		if prop == nil {
+			*issues = append(*issues, SchemaIssue{Path: child, Problem: IssueMissing})
+			continue
+		}
+		compareSchema(inferred.Properties[key], prop, child, issues)
+	}
+	if inferred.Items != nil {
+		child := pathSegment{Kind: segmentDynamic}.join(path)
+		if declared.Items == nil {
+			*issues = append(*issues, SchemaIssue{Path: child, Problem: IssueMissing})
+			return
+		}
+		compareSchema(inferred.Items, declared.Items, child, issues)
+	}
+}
+
+// join appends the segment to path
+func (s pathSegment) join(path string) string {
+	if path == "" {
+		return strings.TrimPrefix(s.String(), ".")
+	}
+	return path + s.String()
+}
+
+func typeAccepts(declared, expected string) bool {
+	return declared == expected || (declared == SchemaInteger && expected == SchemaNumber)
+}
+
+// Validate checks a json context against the schema
+func (s *Schema) Validate(ctx string) []SchemaIssue {
+	var issues []SchemaIssue
+	s.validate(gjson.Parse(ctx), "", &issues)
+	return issues
+}
+
+func (s *Schema) validate(value gjson.Result, path string, issues *[]SchemaIssue) {
+	if s.Type != "" {
+		if got := jsonType(value); !typeAccepts(s.Type, got) {
+			*issues = append(*issues, SchemaIssue{Path: path, Problem: IssueType, Expected: s.Type, Got: got})
+			return
+		}
+	}
+	for _, key := range s.Required {
+		if !value.Get(gjsonEscape(key)).Exists() {
+			child := pathSegment{Kind: segmentKey, Key: key}.join(path)
+			*issues = append(*issues, SchemaIssue{Path: child, Problem: IssueMissing})
+		}
+	}
+	if value.IsObject() {
+		keys := make([]string, 0, len(s.Properties))
+		for key := range s.Properties {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if v := value.Get(gjsonEscape(key)); v.Exists() {
+				s.Properties[key].validate(v, pathSegment{Kind: segmentKey, Key: key}.join(path), issues)
+			}
+		}
+	}
+	if value.IsArray() && s.Items != nil {
+		for i, item := range value.Array() {
+			s.Items.validate(item, pathSegment{Kind: segmentIndex, Index: strconv.Itoa(i)}.join(path), issues)
+		}
+	}
+}
+
+func jsonType(value gjson.Result) string {
+	switch {
+	case value.IsObject():
+		return SchemaObject
+	case value.IsArray():
+		return SchemaArray
+	}
+	switch value.Type {
+	case gjson.Number:
+		return SchemaNumber
+	case gjson.String:
+		return SchemaString
+	case gjson.True, gjson.False:
+		return SchemaBoolean
+	default:
+		return "null"
+	}
+}
+
+// gjsonEscape escapes gjson path syntax in a single key
+func gjsonEscape(key string) string {
+	escaped := strings.Builder{}
+	for _, ch := range key {
+		switch ch {
+		case '.', '*', '?', '|', '#', '@', '\\', '!', '=', '<', '>', '%':
+			escaped.WriteRune('\\')
+		}
+		escaped.WriteRune(ch)
+	}
+	return escaped.String()
+}
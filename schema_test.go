@@ -0,0 +1,112 @@
+package go_template
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplate_InferSchema(t *testing.T) {
+	tp, err := NewTemplate("{$tx.value / 1e18} {$tx.logs[0].topic} {round($price, 2)} {timezone($ts, 8)} {$tx.logs[$i].data}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(tp.InferSchema())
+	assert.JSONEq(t, `{
+		"type": "object",
+		"required": ["tx", "price", "ts", "i"],
+		"properties": {
+			"tx": {
+				"type": "object",
+				"required": ["value", "logs"],
+				"properties": {
+					"value": {"type": "number"},
+					"logs": {
+						"type": "array",
+						"items": {
+							"type": "object",
+							"required": ["topic", "data"],
+							"properties": {"topic": {}, "data": {}}
+						}
+					}
+				}
+			},
+			"price": {"type": "number"},
+			"ts": {},
+			"i": {}
+		}
+	}`, string(data))
+}
+
+func TestTemplate_CheckSchema(t *testing.T) {
+	tp, err := NewTemplate("{$tx.value / 1e18} {$tx.logs[0].topic} {$tx.to}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := &Schema{}
+	err = json.Unmarshal([]byte(`{
+		"type": "object",
+		"properties": {
+			"tx": {
+				"type": "object",
+				"required": ["value"],
+				"properties": {
+					"value": {"type": "string"},
+					"logs": {"type": "array", "items": {"type": "object", "properties": {"topic": {"type": "string"}}}}
+				}
+			}
+		}
+	}`), schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issues := tp.CheckSchema(schema)
+	if len(issues) != 2 {
+		t.Fatalf("expect 2 issues, got %v", issues)
+	}
+	assert.Equal(t, "tx.value: expect number, got string", issues[0].String())
+	assert.Equal(t, "tx.to: missing", issues[1].String())
+	assert.Equal(t, []Position{{Fragment: 4, Line: 1, Column: 41}}, issues[1].Sites)
+	if err := tp.SetSchema(schema); err == nil || tp.Schema != nil {
+		t.Errorf("expect schema rejected, got %v", err)
+	}
+
+	schema.Properties["tx"].Properties["value"].Type = SchemaInteger
+	schema.Properties["tx"].Properties["to"] = &Schema{Type: SchemaString}
+	if err := tp.SetSchema(schema); err != nil {
+		t.Fatal(err)
+	}
+
+	config := &TemplateConfig{ValidateContext: true}
+	_, err = tp.RenderWithConfig(`{"tx": {"value": "1", "logs": [{"topic": 1}]}}`, config)
+	var se *SchemaError
+	if !errors.As(err, &se) {
+		t.Fatalf("expect SchemaError, got %v", err)
+	}
+	assert.Equal(t, "schema mismatch: tx.logs[0].topic: expect string, got number; tx.value: expect integer, got string;", se.Error())
+
+	res, err := tp.RenderWithConfig(`{"tx": {"value": 1e18, "logs": [{"topic": "t"}], "to": "x"}}`, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "1 t x", res)
+}
+
+func TestInferSchema_Concat(t *testing.T) {
+	tp, _ := NewTemplate(`{$symbol + "/USD"} {$a + $b} {$c + 1}`, nil)
+	schema := tp.InferSchema()
+	for name, expect := range map[string]string{"symbol": SchemaString, "a": "", "b": "", "c": SchemaNumber} {
+		if tp := schema.Properties[name].Type; tp != expect {
+			t.Errorf("%s: expect %q, got %q", name, expect, tp)
+		}
+	}
+
+	// + of variables concatenates strings or adds numbers
+	tp, _ = NewTemplateWithConfig("{$first + $last}", nil, &TemplateConfig{ValidateContext: true, Strict: true})
+	tp.SetSchema(tp.InferSchema())
+	if res, err := tp.Render(`{"first": "Bob", "last": "Smith"}`); err != nil || res != "BobSmith" {
+		t.Errorf("expect BobSmith, got %s %v", res, err)
+	}
+}
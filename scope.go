@@ -0,0 +1,26 @@
+package go_template
+
+import (
+	"encoding/json"
+
+	"github.com/tidwall/gjson"
+)
+
+// Scopes are named values supplied at render time, like the recipient's
+// settings or the alert rule. $name resolves to the scope name before the
+// render context, the context keeps serving the other variables.
+// json.RawMessage values are parsed as json, others are used as Go values
+type Scopes map[string]interface{}
+
+// scopeNode converts the scope value to its node, nil for null
+func scopeNode(value interface{}) *ctxNode {
+	raw, ok := value.(json.RawMessage)
+	if !ok {
+		return nativeNode(value)
+	}
+	node := &ctxNode{value: gjson.ParseBytes(raw)}
+	if node.value.Type == gjson.Null {
+		return nil
+	}
+	return node
+}
@@ -0,0 +1,56 @@
+package go_template
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRenderWithScopes(t *testing.T) {
+	tp, err := NewTemplate("{$amount} {$event.amount} {$user.name} {$rule['id']} {formatTime($now)} {$missing}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := `{"amount": 1.5, "user": {"name": "from context"}}`
+	res, err := tp.RenderWithScopes(context.Background(), env, Scopes{
+		"event": json.RawMessage(env),
+		"user":  map[string]interface{}{"name": "alice"},
+		"rule": struct {
+			ID int `json:"id"`
+		}{7},
+		"now":     time.Date(2022, 3, 4, 5, 6, 7, 0, time.UTC),
+		"missing": nil,
+	})
+	if expect := "1.5 1.5 alice 7 2022-03-04 05:06:07Z {$missing}"; err != nil || res != expect {
+		t.Errorf("expect %s, got %s %v", expect, res, err)
+	}
+	// without scopes the context is used
+	res, _ = tp.Render(env)
+	if expect := "1.5 {$event.amount} from context {$rule['id']} {formatTime($now)} {$missing}"; res != expect {
+		t.Errorf("expect %s, got %s", expect, res)
+	}
+}
+
+func TestRenderWithScopes_ValidateContext(t *testing.T) {
+	tp, err := NewTemplateWithConfig("{$user.name}: {$amount}", nil, &TemplateConfig{ValidateContext: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := tp.InferSchema()
+	schema.Properties["user"].Properties["name"].Type = SchemaString
+	if err := tp.SetSchema(schema); err != nil {
+		t.Fatal(err)
+	}
+	res, err := tp.RenderWithScopes(context.Background(), `{"amount": 1}`, Scopes{"user": map[string]interface{}{"name": "alice"}})
+	if err != nil || res != "alice: 1" {
+		t.Errorf("expect alice: 1, got %s %v", res, err)
+	}
+	// the scope is validated instead of the context
+	_, err = tp.RenderWithScopes(context.Background(), `{"amount": 1, "user": {"name": "bob"}}`, Scopes{"user": map[string]interface{}{"name": 1}})
+	var se *SchemaError
+	if !errors.As(err, &se) || len(se.Issues) != 1 || se.Issues[0].Path != "user.name" {
+		t.Errorf("expect user.name mismatch, got %v", err)
+	}
+}
@@ -1,26 +1,42 @@
 package go_template
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
+	"sync/atomic"
 	"time"
 
-	"github.com/sirupsen/logrus"
 	"github.com/tidwall/gjson"
 )
 
 type TemplateConfig struct {
 	TimeOffset int
 	TimeFormat string
+	// ValidateContext checks the context against Template.Schema before rendering
+	ValidateContext bool
+	// Strict fails the render with the error of the first failed fragment,
+	// instead of writing the raw expression back
+	Strict bool
+	// Limits abort renders exceeding them, whether Strict or not
+	Limits Limits
+	// Capabilities granted to the template, functions requiring others are
+	// rejected when parsing, or before rendering with another config
+	Capabilities []string
 }
 
 type Template struct {
 	templateText   string // plain text with variables embeded
-	ctx            string // json string
 	engine         *TemplateEngine
 	parsedTemplate []IFragment
+	spans          []Span // position of each parsed fragment in templateText
 	TemplateConfig *TemplateConfig
+	// Schema is the declared context schema, see SetSchema
+	Schema *Schema
+
+	capsVersion uint64 // capabilities version of the engine TemplateConfig was last checked with
 }
 
 func NewTemplate(text string, engine *TemplateEngine) (*Template, error) {
@@ -28,29 +44,39 @@ func NewTemplate(text string, engine *TemplateEngine) (*Template, error) {
 
 }
 func NewTemplateWithConfig(text string, engine *TemplateEngine, config *TemplateConfig) (*Template, error) {
+	t := newTemplate(text, engine, config)
+	// parse template to fragments
+	fragments, spans, err := t.parseFragments()
+	if err != nil {
+		return nil, err
+	}
+	t.parsedTemplate = fragments
+	t.spans = spans
+	return t, nil
+}
+
+// newTemplate creates a template without fragments
+func newTemplate(text string, engine *TemplateEngine, config *TemplateConfig) *Template {
 	if engine == nil {
 		engine = NewTemplateEngine()
 	}
 	if config == nil {
-		config = &TemplateConfig{
-			TimeOffset: 0,
-			TimeFormat: strings.ReplaceAll(time.RFC3339, "T", " "),
-		}
+		config = defaultConfig()
 	}
-
-	t := &Template{
+	return &Template{
 		templateText:   text,
 		engine:         engine,
-		ctx:            "",
 		TemplateConfig: config,
+		// taken before parsing checks the capabilities
+		capsVersion: engine.FnMgr.capabilitiesVersion(),
 	}
-	// parse template to fragments
-	fragments, err := t.ParseFragments()
-	if err != nil {
-		return nil, err
+}
+
+func defaultConfig() *TemplateConfig {
+	return &TemplateConfig{
+		TimeOffset: 0,
+		TimeFormat: strings.ReplaceAll(time.RFC3339, "T", " "),
 	}
-	t.parsedTemplate = fragments
-	return t, nil
 }
 
 // can't unread more than once, use preifx to represent chars to unread
@@ -104,7 +130,19 @@ func (t *Template) ParseMaybeExpr(reader *strings.Reader, prefix string) (IFragm
 		}
 
 		if bracketCount == 0 {
-			return NewExprFragment(text.String(), t.engine.OperatorsMgr, t.engine.FnMgr)
+			f, err := NewExprFragment(text.String(), t.engine.OperatorsMgr, t.engine.FnMgr)
+			if err != nil {
+				return nil, err
+			}
+			f.Consts = t.engine.ConstMgr
+			f.Hooks = t.engine.Hooks
+			if err := f.Validate(); err != nil {
+				return nil, err
+			}
+			if err := f.CheckCapabilities(t.TemplateConfig.Capabilities); err != nil {
+				return nil, err
+			}
+			return f, nil
 		} else {
 			text.WriteRune(ch)
 		}
@@ -113,71 +151,277 @@ func (t *Template) ParseMaybeExpr(reader *strings.Reader, prefix string) (IFragm
 
 // split template to plain or expr part
 func (t *Template) ParseFragments() ([]IFragment, error) {
+	fragments, _, err := t.parseFragments()
+	return fragments, err
+}
+
+func (t *Template) parseFragments() ([]IFragment, []Span, error) {
 	reader := strings.NewReader(t.templateText)
+	offset := func() int {
+		return int(reader.Size()) - reader.Len()
+	}
 	// loop to read fragment， expr and plain Alternating
 	fragments := []IFragment{}
+	spans := []Span{}
 	for {
+		start := offset()
 		ch, _, err := reader.ReadRune()
 		// EOF
 		if err != nil {
 			break
 		}
 		_ = reader.UnreadRune()
+		var f IFragment
 		if ch == '{' {
-			f, err := t.ParseMaybeExpr(reader, "")
-			if err != nil {
-				return fragments, err
-			}
-			if f != nil {
-				fragments = append(fragments, f)
-			}
+			f, err = t.ParseMaybeExpr(reader, "")
 		} else {
-			f, err := t.ParsePlain(reader, "")
-			if err != nil {
-				return fragments, err
-			}
-			if f != nil {
-				fragments = append(fragments, f)
+			f, err = t.ParsePlain(reader, "")
+		}
+		if err != nil {
+			var te *TemplateError
+			if errors.As(err, &te) {
+				te.locate(t.templateText, len(fragments), Span{Start: start, End: offset()})
 			}
+			return fragments, spans, err
+		}
+		if f != nil {
+			fragments = append(fragments, f)
+			spans = append(spans, Span{Start: start, End: offset()})
 		}
 	}
-	return fragments, nil
-
+	return fragments, spans, nil
 }
 
 func (t *Template) RenderWithConfig(env string, config *TemplateConfig) (string, error) {
+	report, err := t.RenderDetailedWithConfig(env, config)
+	if err != nil {
+		return "", err
+	}
+	return report.Output, nil
+}
+
+// Text returns the template source
+func (t *Template) Text() string {
+	return t.templateText
+}
+
+func (t *Template) Render(env string) (string, error) {
+	return t.RenderWithConfig(env, t.TemplateConfig)
+}
+
+// RenderDetailed renders the template and reports the result of every fragment
+func (t *Template) RenderDetailed(env string) (*RenderReport, error) {
+	return t.RenderDetailedWithConfig(env, t.TemplateConfig)
+}
+
+// RenderDetailedWithConfig returns the report along with the error in strict mode
+func (t *Template) RenderDetailedWithConfig(env string, config *TemplateConfig) (*RenderReport, error) {
+	return t.RenderDetailedContext(context.Background(), env, config)
+}
+
+// RenderContext renders the template, aborting when ctx is done
+func (t *Template) RenderContext(ctx context.Context, env string) (string, error) {
+	report, err := t.RenderDetailedContext(ctx, env, t.TemplateConfig)
+	if err != nil {
+		return "", err
+	}
+	return report.Output, nil
+}
+
+// RenderDetailedContext renders the template, aborting when ctx is done or
+// config.Limits are exceeded. The report of an aborted render holds the
+// fragments rendered until then
+func (t *Template) RenderDetailedContext(ctx context.Context, env string, config *TemplateConfig) (*RenderReport, error) {
+	return t.RenderDetailedWithScopes(ctx, env, config, nil)
+}
+
+// RenderWithScopes renders the template with $name of the scopes resolving
+// to them, other variables resolve against env
+func (t *Template) RenderWithScopes(ctx context.Context, env string, scopes Scopes) (string, error) {
+	report, err := t.RenderDetailedWithScopes(ctx, env, t.TemplateConfig, scopes)
+	if err != nil {
+		return "", err
+	}
+	return report.Output, nil
+}
+
+// RenderDetailedWithScopes is RenderDetailedContext with named scopes
+func (t *Template) RenderDetailedWithScopes(ctx context.Context, env string, config *TemplateConfig, scopes Scopes) (*RenderReport, error) {
 	if config == nil {
 		config = t.TemplateConfig
 	}
-	t.ctx = env
+	s := newEvalState(ctx, env, config)
+	s.scopes = scopes
+	s.resolvers = t.engine.Resolvers
+	hooks := t.engine.Hooks
+	start := time.Now()
+	hooks.beforeRender(t)
 
-	result := ""
-	// eval fragments to string
-	for _, f := range t.parsedTemplate {
-		res, err := f.Eval(t.ctx, config)
+	if config.ValidateContext && t.Schema != nil {
+		// variables of scopes and resolvers are validated too, they are resolved once for the render
+		validated, err := s.schemaContext(t.Schema)
 		if err != nil {
-			logrus.Warnf("failed eval template expression: %s", f.RawContent())
-			//result += fmt.Sprintf("** %s ** ", err)
-			result += "{" + f.RawContent() + "}"
-			continue
+			hooks.afterRender(t, nil, time.Since(start), err)
+			return nil, err
 		}
-		if res == nil {
-			result += "{" + f.RawContent() + "}"
-			continue
+		if issues := t.Schema.Validate(validated); len(issues) > 0 {
+			err := &SchemaError{Issues: issues}
+			hooks.afterRender(t, nil, time.Since(start), err)
+			return nil, err
 		}
+	}
 
-		j, err := json.Marshal(res)
-		if err != nil {
-			logrus.Warnf("failed marshal expr result: %s, err: %s", j, err)
-			result += fmt.Sprintf("** %s ** ", err)
-			continue
+	// the template config was checked when parsing, again if capabilities changed since
+	var capsErr error
+	if config == t.TemplateConfig {
+		capsErr = t.checkCurrentCapabilities()
+	} else {
+		capsErr = t.checkCapabilities(config.Capabilities)
+	}
+	if capsErr != nil {
+		hooks.afterRender(t, nil, time.Since(start), capsErr)
+		return nil, capsErr
+	}
+
+	report := &RenderReport{}
+	output := strings.Builder{}
+	var err error
+	// eval fragments to string
+	for i, f := range t.parsedTemplate {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			err = fmt.Errorf("render aborted: %w", ctxErr)
+			break
+		}
+		hooks.beforeFragment(t, i)
+		fragmentStart := time.Now()
+		r := t.evalFragment(i, f, s)
+		if isAbort(r.Err) {
+			r.Text = ""
+		} else if limitErr := s.checkOutput(output.Len() + len(r.Text)); limitErr != nil {
+			r.Err, r.Text = limitErr, ""
 		}
+		r.Output = Span{Start: output.Len(), End: output.Len() + len(r.Text)}
 		// concat fragments
-		result += gjson.Parse(string(j)).String()
+		output.WriteString(r.Text)
+		report.Fragments = append(report.Fragments, r)
+		hooks.afterFragment(t, r, time.Since(fragmentStart))
+		if isAbort(r.Err) {
+			err = r.Err
+			break
+		}
 	}
+	report.Output = output.String()
+	if failed := report.Failed(); err == nil && config.Strict && len(failed) > 0 {
+		err = failed[0].Err
+	}
+	hooks.afterRender(t, report, time.Since(start), err)
+	return report, err
+}
 
-	return result, nil
+// checkCurrentCapabilities checks TemplateConfig again if capabilities of
+// the engine changed since it was last checked
+func (t *Template) checkCurrentCapabilities() error {
+	version := t.engine.FnMgr.capabilitiesVersion()
+	if atomic.LoadUint64(&t.capsVersion) == version {
+		return nil
+	}
+	if err := t.checkCapabilities(t.TemplateConfig.Capabilities); err != nil {
+		return err
+	}
+	atomic.StoreUint64(&t.capsVersion, version)
+	return nil
 }
-func (t *Template) Render(env string) (string, error) {
-	return t.RenderWithConfig(env, t.TemplateConfig)
+
+// checkCapabilities rejects calls of functions requiring capabilities which are not granted
+func (t *Template) checkCapabilities(granted []string) error {
+	for i, fragment := range t.parsedTemplate {
+		f, ok := fragment.(*ExprFragment)
+		if !ok {
+			continue
+		}
+		if err := f.CheckCapabilities(granted); err != nil {
+			var te *TemplateError
+			if errors.As(err, &te) {
+				te.locate(t.templateText, i, t.spans[i])
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *Template) logFailure(s *evalState, msg string, args ...interface{}) {
+	if t.engine.Logger == nil {
+		return
+	}
+	if t.engine.LogContext {
+		args = append(args, "context", s.ctx)
+	}
+	t.engine.Logger.Warn(msg, args...)
+}
+
+func (t *Template) evalFragment(i int, f IFragment, s *evalState) *FragmentResult {
+	r := &FragmentResult{
+		Index:  i,
+		Raw:    f.RawContent(),
+		Source: t.spans[i],
+	}
+	var res interface{}
+	var err error
+	if expr, ok := f.(*ExprFragment); ok {
+		r.IsExpr = true
+		res, err = expr.evalContent(expr.Content, s)
+		if err == nil {
+			r.Value = res
+			res, r.Formatting = formatResult(res)
+		}
+	} else {
+		res, err = f.Eval(s.ctx, s.config)
+		r.Value = res
+		r.Formatting = FormattingPlain
+	}
+	if r.Value != nil {
+		r.Type = fmt.Sprintf("%T", r.Value)
+	}
+	if err != nil {
+		var te *TemplateError
+		if errors.As(err, &te) {
+			te.locate(t.templateText, i, r.Source)
+		}
+		t.logFailure(s, "failed eval template expression", "expr", f.RawContent(), "err", err)
+		r.Err = err
+		r.Formatting = FormattingRaw
+		r.Text = "{" + f.RawContent() + "}"
+		return r
+	}
+	if res == nil {
+		r.Formatting = FormattingRaw
+		r.Text = "{" + f.RawContent() + "}"
+		return r
+	}
+
+	text, err := outputText(res)
+	if err != nil {
+		t.logFailure(s, "failed marshal expr result", "expr", f.RawContent(), "err", err)
+		r.Err = err
+		r.Text = fmt.Sprintf("** %s ** ", err)
+		return r
+	}
+	r.Text = text
+	return r
+}
+
+// outputText converts a formatted value to the text written to the output
+func outputText(value interface{}) (string, error) {
+	j, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return gjson.Parse(string(j)).String(), nil
+}
+
+// FormatValue formats an evaluated expression value as a render writes it
+func FormatValue(value interface{}) (string, error) {
+	formatted, _ := formatResult(value)
+	return outputText(formatted)
 }
@@ -1,6 +1,7 @@
 package go_template
 
 import (
+	"errors"
 	"testing"
 )
 
@@ -36,3 +37,96 @@ func TestSyntaxError(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestTemplate_RenderDetailed(t *testing.T) {
+	tp, err := NewTemplate("a {$a.b * 1000} b {$a.c} c", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := tp.RenderDetailed(`{"a": {"b": 333}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Output != "a 333,000 b {$a.c} c" {
+		t.Errorf("unexpected output: %s", report.Output)
+	}
+	if len(report.Fragments) != 5 {
+		t.Fatalf("expect 5 fragments, got %d", len(report.Fragments))
+	}
+	num := report.Fragments[1]
+	if !num.IsExpr || num.Raw != "$a.b * 1000" || num.Formatting != FormattingNumber || num.Type != "decimal.Decimal" {
+		t.Errorf("unexpected fragment result: %+v", num)
+	}
+	if num.Source != (Span{2, 15}) || num.Output != (Span{2, 9}) {
+		t.Errorf("unexpected spans: %+v %+v", num.Source, num.Output)
+	}
+	if report.Output[num.Output.Start:num.Output.End] != "333,000" {
+		t.Errorf("output span mismatch: %+v", num.Output)
+	}
+	failed := report.Failed()
+	if len(failed) != 1 || failed[0].Index != 3 || failed[0].Formatting != FormattingRaw {
+		t.Errorf("unexpected failed fragments: %+v", failed)
+	}
+}
+
+type recordLogger struct {
+	msgs [][]interface{}
+}
+
+func (l *recordLogger) Warn(msg string, args ...interface{}) {
+	l.msgs = append(l.msgs, append([]interface{}{msg}, args...))
+}
+
+func TestTemplate_Logger(t *testing.T) {
+	engine := NewTemplateEngine()
+	logger := &recordLogger{}
+	engine.Logger = logger
+	tp, _ := NewTemplate("{$a.c} {$a.b}", engine)
+	ctx := `{"a": {"b": "secret"}}`
+	_, _ = tp.Render(ctx)
+	if len(logger.msgs) != 1 {
+		t.Fatalf("expect 1 warning, got %v", logger.msgs)
+	}
+	for _, arg := range logger.msgs[0] {
+		if arg == ctx {
+			t.Errorf("context logged without LogContext: %v", logger.msgs[0])
+		}
+	}
+
+	engine.LogContext = true
+	_, _ = tp.Render(ctx)
+	last := logger.msgs[1]
+	if last[len(last)-2] != "context" || last[len(last)-1] != ctx {
+		t.Errorf("expect context logged, got %v", last)
+	}
+}
+
+func TestTemplate_Render_Strict(t *testing.T) {
+	tp, _ := NewTemplate("{$a.b} {$a.c}", nil)
+	res, err := tp.RenderWithConfig(`{"a": {"b": 333}}`, &TemplateConfig{Strict: true})
+	var te *TemplateError
+	if !errors.As(err, &te) || te.Kind != ErrKindUnknownVariable || te.Fragment != 2 {
+		t.Errorf("expect unknown variable error, got %v", err)
+	}
+	if res != "" {
+		t.Errorf("expect empty result, got %s", res)
+	}
+}
+
+func TestScanTemplate(t *testing.T) {
+	segments := ScanTemplate("a {$b + {}} c} {d")
+	expect := []Segment{
+		{Span: Span{0, 2}, Content: "a "},
+		{Span: Span{2, 11}, Expr: true, Closed: true, Content: "$b + {}"},
+		{Span: Span{11, 15}, Content: " c} "},
+		{Span: Span{15, 17}, Expr: true, Content: "d"},
+	}
+	if len(segments) != len(expect) {
+		t.Fatalf("expect %v, got %v", expect, segments)
+	}
+	for i := range expect {
+		if segments[i] != expect[i] {
+			t.Errorf("expect %+v, got %+v", expect[i], segments[i])
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package go_template
 
 import (
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -42,3 +44,43 @@ func FormatTime(t time.Time, offset int, format string) string {
 
 	return t.In(time.FixedZone(timezoneName, int(offset*3600))).Format(format)
 }
+
+// lineColumn converts a byte offset in text to 1-based line and column
+func lineColumn(text string, offset int) (int, int) {
+	line, column := 1, 1
+	for _, ch := range text[:offset] {
+		if ch == '\n' {
+			line += 1
+			column = 1
+		} else {
+			column += 1
+		}
+	}
+	return line, column
+}
+
+// offsetOf converts 1-based line and column in text to a byte offset
+func offsetOf(text string, line, column int) int {
+	offset := 0
+	for l := 1; l < line; l++ {
+		i := strings.IndexByte(text[offset:], '\n')
+		if i < 0 {
+			return -1
+		}
+		offset += i + 1
+	}
+	offset += column - 1
+	if offset < 0 || offset > len(text) {
+		return -1
+	}
+	return offset
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
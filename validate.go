@@ -0,0 +1,119 @@
+package go_template
+
+import (
+	"strconv"
+)
+
+// Validate checks function calls in the expression against functions
+// registered in FnMgr and their signatures
+func (f *ExprFragment) Validate() error {
+	var err *TemplateError
+	walkNode(f.Node, func(n *Node) bool {
+		if err != nil {
+			return false
+		}
+		if n.Op != OpCall {
+			return true
+		}
+		err = f.validateCall(n)
+		return err == nil
+	})
+	if err != nil {
+		return f.withSource(err)
+	}
+	return nil
+}
+
+// CheckCapabilities rejects calls of functions requiring capabilities which
+// are not in granted
+func (f *ExprFragment) CheckCapabilities(granted []string) error {
+	var err *TemplateError
+	walkNode(f.Node, func(n *Node) bool {
+		if err != nil {
+			return false
+		}
+		if n.Op == OpCall {
+			for _, c := range f.FnMgr.GetCapabilities(n.Name) {
+				if !hasCapability(granted, c) {
+					err = newTemplateError(ErrKindNotAllowed, n.Pos, "%s requires capability %s", n.Name, c)
+					break
+				}
+			}
+		}
+		return err == nil
+	})
+	if err != nil {
+		return f.withSource(err)
+	}
+	return nil
+}
+
+func hasCapability(granted []string, c string) bool {
+	for _, g := range granted {
+		if g == c {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *ExprFragment) validateCall(n *Node) *TemplateError {
+	name := n.Name
+	if name == "" {
+		return newTemplateError(ErrKindSyntax, n.Pos, "only named functions can be called")
+	}
+	if f.FnMgr.GetFunc(name) == nil {
+		return newTemplateError(ErrKindUnknownFunction, n.Pos, "%s", name)
+	}
+	sig := f.FnMgr.GetSignature(name)
+	if sig == nil {
+		return nil
+	}
+	if len(n.Args) < sig.MinArgs || (sig.MaxArgs != Variadic && len(n.Args) > sig.MaxArgs) {
+		return newTemplateError(ErrKindType, n.Pos, "%s expects %s args, got %d", name, sig.arity(), len(n.Args))
+	}
+	for i, arg := range n.Args {
+		want := sig.ArgKind(i)
+		got, ok := literalKind(arg)
+		if ok && !kindAccepts(want, got) {
+			return newTemplateError(ErrKindType, arg.Pos, "%s arg%d must be %s, got %s", name, i, want, got)
+		}
+	}
+	return nil
+}
+
+func (s *Signature) arity() string {
+	switch {
+	case s.MaxArgs == Variadic:
+		return "at least " + strconv.Itoa(s.MinArgs)
+	case s.MinArgs == s.MaxArgs:
+		return strconv.Itoa(s.MinArgs)
+	default:
+		return strconv.Itoa(s.MinArgs) + " to " + strconv.Itoa(s.MaxArgs)
+	}
+}
+
+// literalKind returns kind of literal args, other expressions are only known at render time
+func literalKind(n *Node) (ArgKind, bool) {
+	switch n.Op {
+	case OpNumber:
+		return ArgNumber, true
+	case OpString:
+		return ArgString, true
+	case OpBool:
+		return ArgBool, true
+	default:
+		return "", false
+	}
+}
+
+func kindAccepts(want, got ArgKind) bool {
+	switch want {
+	case ArgAny, got:
+		return true
+	case ArgTime:
+		return got == ArgNumber || got == ArgString
+	default:
+		return false
+	}
+}
@@ -0,0 +1,110 @@
+package go_template
+
+import (
+	"errors"
+	"testing"
+)
+
+func capabilityEngine(t *testing.T) *TemplateEngine {
+	engine := NewTemplateEngine()
+	if err := engine.FnMgr.RegisterFunc("price", func(_ *TemplateConfig, args []interface{}) (interface{}, error) {
+		return "42", nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	engine.FnMgr.SetCapabilities("price", "internal")
+	return engine
+}
+
+func TestCapabilities_Parse(t *testing.T) {
+	engine := capabilityEngine(t)
+	_, err := NewTemplate("a {round(price(), 1)}", engine)
+	var te *TemplateError
+	if !errors.As(err, &te) || te.Kind != ErrKindNotAllowed || te.Column != 10 {
+		t.Fatalf("expect price not allowed at 1:10, got %v", err)
+	}
+
+	tp, err := NewTemplateWithConfig("a {price()}", engine, &TemplateConfig{Capabilities: []string{"internal"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res, err := tp.Render("{}"); err != nil || res != "a 42" {
+		t.Errorf("expect a 42, got %s %v", res, err)
+	}
+
+	// granted by another render config
+	_, err = tp.RenderWithConfig("{}", &TemplateConfig{})
+	if !errors.As(err, &te) || te.Kind != ErrKindNotAllowed {
+		t.Errorf("expect price not allowed, got %v", err)
+	}
+	if _, err := tp.RenderWithConfig("{}", &TemplateConfig{Capabilities: []string{"internal", "x"}}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCapabilities_Fork(t *testing.T) {
+	engine := capabilityEngine(t)
+	tenant := engine.Fork()
+	if caps := tenant.FnMgr.GetCapabilities("price"); len(caps) != 1 || caps[0] != "internal" {
+		t.Errorf("expect inherited capabilities, got %v", caps)
+	}
+	// shadowing keeps the capabilities until they are cleared
+	if err := tenant.FnMgr.RegisterFunc("price", func(_ *TemplateConfig, args []interface{}) (interface{}, error) {
+		return "0", nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewTemplate("{price()}", tenant); err == nil {
+		t.Error("expect price not allowed in child")
+	}
+	tenant.FnMgr.SetCapabilities("price")
+	if _, err := NewTemplate("{price()}", tenant); err != nil {
+		t.Error(err)
+	}
+	if _, err := NewTemplate("{price()}", engine); err == nil {
+		t.Error("expect price not allowed in parent")
+	}
+}
+
+func TestCapabilities_Reregister(t *testing.T) {
+	engine := capabilityEngine(t)
+	if err := engine.FnMgr.RegisterFunc("price", func(_ *TemplateConfig, args []interface{}) (interface{}, error) {
+		return "43", nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewTemplate("{price()}", engine); err == nil {
+		t.Error("expect price still not allowed")
+	}
+}
+
+func TestCapabilities_TaggedAfterParse(t *testing.T) {
+	engine := NewTemplateEngine()
+	if err := engine.FnMgr.RegisterFunc("price", func(_ *TemplateConfig, args []interface{}) (interface{}, error) {
+		return "42", nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	tp, err := engine.Get("{price()}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tenant := engine.Fork()
+	child, _ := tenant.Get("{price()}")
+	engine.FnMgr.SetCapabilities("price", "internal")
+
+	var te *TemplateError
+	for _, tp := range []*Template{tp, child} {
+		if _, err := tp.Render("{}"); !errors.As(err, &te) || te.Kind != ErrKindNotAllowed {
+			t.Errorf("expect price not allowed on render, got %v", err)
+		}
+	}
+	if _, err := engine.Get("{price()}"); !errors.As(err, &te) || te.Kind != ErrKindNotAllowed {
+		t.Errorf("expect cached template rejected, got %v", err)
+	}
+
+	engine.FnMgr.SetCapabilities("price")
+	if res, err := tp.Render("{}"); err != nil || res != "42" {
+		t.Errorf("expect 42 once cleared, got %s %v", res, err)
+	}
+}
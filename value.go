@@ -0,0 +1,205 @@
+package go_template
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Value is a value of an expression with its kind, see KindOf. Functions
+// and operators written against Value get uniform conversions and errors
+// instead of asserting interface{} values themselves
+type Value struct {
+	kind Kind
+	raw  interface{}
+}
+
+// NewValue wraps a Go value, Go and json numbers are converted to decimal.Decimal.
+// Numeric strings are numbers, use StringValue to keep them strings
+func NewValue(raw interface{}) Value {
+	if v, ok := raw.(Value); ok {
+		return v
+	}
+	if d, ok := toDecimal(raw); ok {
+		raw = d
+	}
+	return Value{kind: KindOf(raw), raw: raw}
+}
+
+func NullValue() Value                        { return Value{kind: KindNull} }
+func NumberValue(d decimal.Decimal) Value     { return Value{kind: KindNumber, raw: d} }
+func StringValue(s string) Value              { return Value{kind: KindString, raw: s} }
+func BoolValue(b bool) Value                  { return Value{kind: KindBool, raw: b} }
+func TimeValue(t time.Time) Value             { return Value{kind: KindTime, raw: t} }
+func DurationValue(d time.Duration) Value     { return Value{kind: KindDuration, raw: d} }
+func CustomValue(raw interface{}) Value       { return Value{kind: KindCustom, raw: raw} }
+func ListValue(items ...Value) Value          { return Value{kind: KindList, raw: unwrapList(items)} }
+func MapValue(entries map[string]Value) Value { return Value{kind: KindMap, raw: unwrapMap(entries)} }
+
+// Kind returns the kind of the value, the zero Value is null
+func (v Value) Kind() Kind {
+	if v.kind == "" {
+		return KindNull
+	}
+	return v.kind
+}
+
+// Interface returns the Go value, as functions and operators with
+// interface{} signatures receive it
+func (v Value) Interface() interface{} {
+	return v.raw
+}
+
+func (v Value) IsNull() bool {
+	return v.raw == nil
+}
+
+func (v Value) typeError(want Kind) error {
+	return newTemplateError(ErrKindType, -1, "expect %s, got %s: %v", want, v.Kind(), v.raw)
+}
+
+// AsNumber converts numbers and numeric strings
+func (v Value) AsNumber() (decimal.Decimal, error) {
+	if d, ok := toDecimal(v.raw); ok {
+		return d, nil
+	}
+	if s, ok := v.raw.(string); ok {
+		if d, err := decimal.NewFromString(s); err == nil {
+			return d, nil
+		}
+	}
+	return decimal.Decimal{}, v.typeError(KindNumber)
+}
+
+// AsString returns strings, numeric strings included
+func (v Value) AsString() (string, error) {
+	if s, ok := v.raw.(string); ok {
+		return s, nil
+	}
+	return "", v.typeError(KindString)
+}
+
+func (v Value) AsBool() (bool, error) {
+	if b, ok := v.raw.(bool); ok {
+		return b, nil
+	}
+	return false, v.typeError(KindBool)
+}
+
+// AsTime converts times, time strings and timestamps, strings are parsed
+// with the formats of the time functions
+func (v Value) AsTime(config *TemplateConfig) (time.Time, error) {
+	if v.kind == KindNumber || v.kind == KindString || v.kind == KindTime {
+		if config == nil {
+			config = &TemplateConfig{}
+		}
+		if t, err := toTime(v.raw, config); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, v.typeError(KindTime)
+}
+
+// AsDuration converts durations and duration strings like 1h30m
+func (v Value) AsDuration() (time.Duration, error) {
+	switch raw := v.raw.(type) {
+	case time.Duration:
+		return raw, nil
+	case string:
+		if d, err := time.ParseDuration(raw); err == nil {
+			return d, nil
+		}
+	}
+	return 0, v.typeError(KindDuration)
+}
+
+func (v Value) AsList() ([]Value, error) {
+	items, ok := v.raw.([]interface{})
+	if !ok {
+		return nil, v.typeError(KindList)
+	}
+	values := make([]Value, 0, len(items))
+	for _, item := range items {
+		values = append(values, NewValue(item))
+	}
+	return values, nil
+}
+
+func (v Value) AsMap() (map[string]Value, error) {
+	entries, ok := v.raw.(map[string]interface{})
+	if !ok {
+		return nil, v.typeError(KindMap)
+	}
+	values := make(map[string]Value, len(entries))
+	for key, entry := range entries {
+		values[key] = NewValue(entry)
+	}
+	return values, nil
+}
+
+func (v Value) String() string {
+	if s, ok := v.raw.(string); ok {
+		return s
+	}
+	if v.raw == nil {
+		return "null"
+	}
+	if v.kind == KindList || v.kind == KindMap {
+		if j, err := json.Marshal(v.raw); err == nil {
+			return string(j)
+		}
+	}
+	return fmt.Sprint(v.raw)
+}
+
+func unwrapList(items []Value) []interface{} {
+	raw := make([]interface{}, 0, len(items))
+	for _, item := range items {
+		raw = append(raw, item.raw)
+	}
+	return raw
+}
+
+func unwrapMap(entries map[string]Value) map[string]interface{} {
+	raw := make(map[string]interface{}, len(entries))
+	for key, entry := range entries {
+		raw[key] = entry.raw
+	}
+	return raw
+}
+
+// ValueFn is a function taking and returning Values, register it with
+// ValueFn.IFn
+type ValueFn func(config *TemplateConfig, args []Value) (Value, error)
+
+// IFn adapts fn to the interface{} signature FnMgr registers
+func (fn ValueFn) IFn() IFn {
+	return func(config *TemplateConfig, args []interface{}) (interface{}, error) {
+		values := make([]Value, 0, len(args))
+		for _, arg := range args {
+			values = append(values, NewValue(arg))
+		}
+		result, err := fn(config, values)
+		if err != nil {
+			return nil, err
+		}
+		return result.raw, nil
+	}
+}
+
+// ValueOperator is an operator taking and returning Values, register it
+// with ValueOperator.IOperator
+type ValueOperator func(left, right Value) (Value, error)
+
+// IOperator adapts op to the interface{} signature OperatorsMgr registers
+func (op ValueOperator) IOperator() IOperator {
+	return func(arg1, arg2 interface{}) (interface{}, error) {
+		result, err := op(NewValue(arg1), NewValue(arg2))
+		if err != nil {
+			return nil, err
+		}
+		return result.raw, nil
+	}
+}
@@ -0,0 +1,110 @@
+package go_template
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestNewValue(t *testing.T) {
+	cases := []struct {
+		raw  interface{}
+		kind Kind
+	}{
+		{nil, KindNull},
+		{int64(3), KindNumber},
+		{json.Number("1.5"), KindNumber},
+		{"12", KindNumber},
+		{"abc", KindString},
+		{true, KindBool},
+		{time.Now(), KindTime},
+		{time.Hour, KindDuration},
+		{[]interface{}{1}, KindList},
+		{map[string]interface{}{"a": 1}, KindMap},
+		{struct{}{}, KindCustom},
+	}
+	for _, c := range cases {
+		if kind := NewValue(c.raw).Kind(); kind != c.kind {
+			t.Errorf("%T: expect %s, got %s", c.raw, c.kind, kind)
+		}
+	}
+	if d, ok := NewValue(int64(3)).Interface().(decimal.Decimal); !ok || !d.Equal(decimal.NewFromInt(3)) {
+		t.Error("expect numbers converted to decimal")
+	}
+	if (Value{}).Kind() != KindNull || !NullValue().IsNull() {
+		t.Error("expect zero value null")
+	}
+}
+
+func TestValue_Conversions(t *testing.T) {
+	if n, err := StringValue("1.5").AsNumber(); err != nil || n.String() != "1.5" {
+		t.Errorf("expect 1.5, got %v %v", n, err)
+	}
+	if s, err := StringValue("1.5").AsString(); err != nil || s != "1.5" {
+		t.Errorf("expect numeric string, got %v %v", s, err)
+	}
+	if kind := StringValue("1.5").Kind(); kind != KindString {
+		t.Errorf("expect string, got %s", kind)
+	}
+	if d, err := StringValue("90m").AsDuration(); err != nil || d != 90*time.Minute {
+		t.Errorf("expect 90m, got %v %v", d, err)
+	}
+	if tm, err := NumberValue(decimal.NewFromInt(1609459200)).AsTime(nil); err != nil || tm.UTC().Year() != 2021 {
+		t.Errorf("expect 2021, got %v %v", tm, err)
+	}
+	items, err := NewValue([]interface{}{"a", 1.5}).AsList()
+	if err != nil || len(items) != 2 || items[1].Kind() != KindNumber {
+		t.Errorf("unexpected list %v %v", items, err)
+	}
+	if s := MapValue(map[string]Value{"a": ListValue(BoolValue(true), NullValue())}).String(); s != `{"a":[true,null]}` {
+		t.Errorf("unexpected map %s", s)
+	}
+
+	_, err = StringValue("abc").AsNumber()
+	var te *TemplateError
+	if !errors.As(err, &te) || te.Kind != ErrKindType || te.Message != "expect number, got string: abc" {
+		t.Errorf("unexpected error %v", err)
+	}
+	if _, err := BoolValue(true).AsMap(); err == nil {
+		t.Error("expect bool not a map")
+	}
+}
+
+func TestValueFn(t *testing.T) {
+	engine := NewTemplateEngine()
+	if err := engine.FnMgr.RegisterFuncWithSignature("repeat", ValueFn(func(_ *TemplateConfig, args []Value) (Value, error) {
+		s, err := args[0].AsString()
+		if err != nil {
+			return NullValue(), err
+		}
+		n, err := args[1].AsNumber()
+		if err != nil {
+			return NullValue(), err
+		}
+		return StringValue(strings.Repeat(s, int(n.IntPart()))), nil
+	}).IFn(), Signature{MinArgs: 2, MaxArgs: 2, Args: []ArgKind{ArgString, ArgNumber}}); err != nil {
+		t.Fatal(err)
+	}
+	engine.OperatorsMgr.RegisterTyped("*", KindDuration, KindNumber, ValueOperator(func(left, right Value) (Value, error) {
+		d, _ := left.AsDuration()
+		n, err := right.AsNumber()
+		return DurationValue(time.Duration(n.Mul(decimal.NewFromInt(int64(d))).IntPart())), err
+	}).IOperator())
+
+	tp, err := NewTemplate("{repeat($s, 2)} {duration('1m') * 1.5} {repeat(round($n, 0), 2)}", engine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, _ := tp.RenderDetailed(`{"s": "ab", "n": 1.2}`)
+	if report.Output != "abab 1m30s {repeat(round($n, 0), 2)}" {
+		t.Errorf("unexpected output %s", report.Output)
+	}
+	var te *TemplateError
+	if err := report.Fragments[4].Err; !errors.As(err, &te) || te.Message != "expect string, got number: 1" {
+		t.Errorf("unexpected error %v", err)
+	}
+}